@@ -4,28 +4,35 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/logging"
 	"github.com/shogotsuneto/simple-query-server/internal/server"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	var (
 		dbConfigPath      = flag.String("db-config", "", "Path to database configuration YAML file")
-		queriesConfigPath = flag.String("queries-config", "", "Path to queries configuration YAML file")
+		queriesConfigPath = flag.String("queries-config", "", "Path to queries configuration YAML file, or a directory or glob pattern of YAML files")
 		serverConfigPath  = flag.String("server-config", "", "Path to server configuration YAML file (optional)")
 		port              = flag.String("port", "8080", "Port to run the server on")
+		watchConfig       = flag.Bool("watch-config", false, "Reload the queries and server configs when their files change")
 		help              = flag.Bool("help", false, "Show help message")
 	)
 	flag.Parse()
 
 	if *help {
 		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s validate [options]  Check the queries against the database and exit\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 		os.Exit(0)
@@ -37,35 +44,41 @@ func main() {
 		os.Exit(1)
 	}
 
-	log.Printf("Starting simple-query-server...")
-	log.Printf("Database config: %s", *dbConfigPath)
-	log.Printf("Queries config: %s", *queriesConfigPath)
-	if *serverConfigPath != "" {
-		log.Printf("Server config: %s", *serverConfigPath)
-	}
-	log.Printf("Port: %s", *port)
+	// Log with the defaults until the server config is loaded
+	logging.Setup(os.Stderr, config.LoggingConfig{})
+
+	slog.Info("Starting simple-query-server...")
+	slog.Info("Configuration",
+		"db_config", *dbConfigPath,
+		"queries_config", *queriesConfigPath,
+		"server_config", *serverConfigPath,
+		"port", *port,
+	)
 
 	// Load configurations
 	dbConfig, err := config.LoadDatabaseConfig(*dbConfigPath)
 	if err != nil {
-		log.Fatalf("Failed to load database config: %v", err)
+		fatal("Failed to load database config", err)
 	}
 
 	queriesConfig, err := config.LoadQueriesConfig(*queriesConfigPath)
 	if err != nil {
-		log.Fatalf("Failed to load queries config: %v", err)
+		fatal("Failed to load queries config", err)
 	}
 
-	log.Printf("Loaded %d queries from configuration", len(queriesConfig.Queries))
+	slog.Info("Loaded queries from configuration", "count", len(queriesConfig.Queries))
 
 	// Load server configuration if provided
 	var serverConfig *config.ServerConfig
 	if *serverConfigPath != "" {
 		serverConfig, err = config.LoadServerConfig(*serverConfigPath)
 		if err != nil {
-			log.Fatalf("Failed to load server config: %v", err)
+			fatal("Failed to load server config", err)
+		}
+		if err := logging.Setup(os.Stderr, serverConfig.Logging); err != nil {
+			fatal("Failed to set up logging", err)
 		}
-		log.Printf("Loaded %d middleware configurations", len(serverConfig.Middleware))
+		slog.Info("Loaded middleware configurations", "count", len(serverConfig.Middleware))
 	}
 
 	// Create context for graceful shutdown
@@ -79,7 +92,23 @@ func main() {
 	// Start HTTP server
 	srv, err := server.New(dbConfig, queriesConfig, serverConfig)
 	if err != nil {
-		log.Fatalf("Failed to create server: %v", err)
+		fatal("Failed to create server", err)
+	}
+	srv.SetConfigPaths(*queriesConfigPath, *serverConfigPath)
+
+	// Reload the configuration on SIGHUP, and on file changes if enabled
+	reloadCh := make(chan os.Signal, 1)
+	signal.Notify(reloadCh, syscall.SIGHUP)
+	go func() {
+		for range reloadCh {
+			slog.Info("Received SIGHUP, reloading configuration")
+			if err := srv.Reload(); err != nil {
+				slog.Error("Configuration reload failed, keeping the current configuration", "error", err)
+			}
+		}
+	}()
+	if *watchConfig {
+		go srv.WatchConfig(ctx)
 	}
 
 	// Start server in a goroutine
@@ -93,9 +122,9 @@ func main() {
 	// Wait for either a signal or server error
 	select {
 	case err := <-serverErrors:
-		log.Fatalf("Server failed to start: %v", err)
+		fatal("Server failed to start", err)
 	case sig := <-sigCh:
-		log.Printf("Received signal %s, initiating graceful shutdown...", sig)
+		slog.Info("Received signal, initiating graceful shutdown...", "signal", sig.String())
 		cancel()
 
 		// Give the server a moment to shut down gracefully
@@ -104,9 +133,15 @@ func main() {
 
 		select {
 		case <-srv.Done():
-			log.Printf("Server shutdown completed")
+			slog.Info("Server shutdown completed")
 		case <-shutdownTimeout.C:
-			log.Printf("Server shutdown timeout, forcing exit")
+			slog.Warn("Server shutdown timeout, forcing exit")
 		}
 	}
 }
+
+// fatal logs an error and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+// runValidate implements the validate subcommand: it loads the configuration, prepares the
+// statement of every query against the database without executing it and reports the
+// inferred types. It returns the exit code: 0 when everything is valid, 1 otherwise.
+func runValidate(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var (
+		dbConfigPath      = flags.String("db-config", "", "Path to database configuration YAML file")
+		queriesConfigPath = flags.String("queries-config", "", "Path to queries configuration YAML file, or a directory or glob pattern of YAML files")
+		serverConfigPath  = flags.String("server-config", "", "Path to server configuration YAML file, to check middleware parameters (optional)")
+		timeout           = flags.Duration("timeout", 30*time.Second, "Time allowed for connecting to the database and checking the queries")
+	)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s validate --db-config ./database.yaml --queries-config ./queries.yaml\n", os.Args[0])
+		fmt.Fprintf(stderr, "Options:\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if *dbConfigPath == "" || *queriesConfigPath == "" {
+		fmt.Fprintf(stderr, "Error: Both --db-config and --queries-config are required\n\n")
+		flags.Usage()
+		return 2
+	}
+
+	dbConfig, err := config.LoadDatabaseConfig(*dbConfigPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	queriesConfig, err := config.LoadQueriesConfig(*queriesConfigPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *serverConfigPath != "" {
+		if err := checkMiddlewareParams(*serverConfigPath, queriesConfig); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	db, err := openDatabase(dbConfig)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	// Queries are prepared one after the other on a single connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: failed to connect to PostgreSQL database: %v\n", err)
+		return 1
+	}
+	defer conn.Close()
+
+	names := make([]string, 0, len(queriesConfig.Queries))
+	for name := range queriesConfig.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	failed := 0
+	for _, name := range names {
+		types, err := query.PrepareStatement(ctx, conn, queriesConfig.Queries[name])
+		if err != nil {
+			failed++
+			fmt.Fprintf(stdout, "FAIL %s: %v\n", name, err)
+			continue
+		}
+
+		fmt.Fprintf(stdout, "ok   %s\n", name)
+		params := make([]string, len(types.Params))
+		for i, param := range types.Params {
+			params[i] = param.Name + " " + param.Type
+		}
+		if len(params) > 0 {
+			fmt.Fprintf(stdout, "       params:  %s\n", strings.Join(params, ", "))
+		}
+		if len(types.Results) > 0 {
+			fmt.Fprintf(stdout, "       results: %s\n", strings.Join(types.Results, ", "))
+		}
+	}
+
+	fmt.Fprintf(stdout, "%d queries checked, %d failed\n", len(names), failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// checkMiddlewareParams checks that the middleware of a server config produces every
+// middleware parameter the queries declare
+func checkMiddlewareParams(serverConfigPath string, queriesConfig *config.QueriesConfig) error {
+	serverConfig, err := config.LoadServerConfig(serverConfigPath)
+	if err != nil {
+		return err
+	}
+	chain, err := middleware.CreateMiddlewareChain(serverConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create middleware chain: %w", err)
+	}
+	defer chain.Close()
+	return queriesConfig.CheckMiddlewareParams(chain.ProducedParams())
+}
+
+// openDatabase opens the configured database
+func openDatabase(dbConfig *config.DatabaseConfig) (*sql.DB, error) {
+	dsn, err := dbConfig.ReadDSN()
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PostgreSQL connection: %w", err)
+	}
+	return db, nil
+}
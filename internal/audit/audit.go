@@ -0,0 +1,145 @@
+// Package audit records an append-only trail of query executions: who ran which query,
+// when, with which parameters, and with which outcome
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+)
+
+// defaultBufferSize is the number of entries waiting to be written when not configured
+const defaultBufferSize = 1024
+
+// Outcomes of audited executions
+const (
+	OutcomeSuccess     = "success"
+	OutcomeClientError = "client_error"
+	OutcomeError       = "error"
+)
+
+// Entry is the audit record of a single query execution
+type Entry struct {
+	Time       time.Time              `json:"time"`
+	RequestID  string                 `json:"request_id,omitempty"`
+	Query      string                 `json:"query"`
+	Identity   map[string]interface{} `json:"identity,omitempty"` // Middleware parameters identifying the caller
+	Params     map[string]interface{} `json:"params,omitempty"`   // Parameters with sensitive values redacted
+	Rows       int                    `json:"rows"`
+	Outcome    string                 `json:"outcome"`
+	Error      string                 `json:"error,omitempty"`
+	DurationMs float64                `json:"duration_ms"`
+}
+
+// Sink is a destination of audit entries
+type Sink interface {
+	// Write appends an entry to the sink
+	Write(entry Entry) error
+
+	// Close flushes and releases the sink
+	Close() error
+}
+
+// Logger writes audit entries to its sinks in the background, in the order they were
+// recorded. Recording blocks while the buffer is full, so no entry is dropped.
+type Logger struct {
+	auditConfig config.AuditConfig
+	sinks       []namedSink
+	entries     chan Entry
+	done        chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// namedSink is a sink with the type it was configured with, for error messages
+type namedSink struct {
+	Sink
+	name string
+}
+
+// New creates the configured sinks and starts writing entries. Sinks of type postgres
+// connect to the database of dbConfig unless they declare their own DSN.
+func New(auditConfig config.AuditConfig, dbConfig *config.DatabaseConfig) (*Logger, error) {
+	sinks := make([]namedSink, 0, len(auditConfig.Sinks))
+	for _, sinkConfig := range auditConfig.Sinks {
+		sink, err := newSink(sinkConfig, dbConfig)
+		if err != nil {
+			for _, created := range sinks {
+				created.Close()
+			}
+			return nil, fmt.Errorf("failed to create audit sink %s: %w", sinkConfig.Type, err)
+		}
+		sinks = append(sinks, namedSink{Sink: sink, name: sinkConfig.Type})
+	}
+
+	bufferSize := auditConfig.BufferSize
+	if bufferSize == 0 {
+		bufferSize = defaultBufferSize
+	}
+
+	l := &Logger{
+		auditConfig: auditConfig,
+		sinks:       sinks,
+		entries:     make(chan Entry, bufferSize),
+		done:        make(chan struct{}),
+	}
+	go l.run()
+	return l, nil
+}
+
+// Audits reports whether executions of the query are recorded
+func (l *Logger) Audits(queryConfig config.Query) bool {
+	return l.auditConfig.AllQueries || queryConfig.Audit
+}
+
+// Record queues an entry for writing. Entries recorded after Close are discarded.
+func (l *Logger) Record(entry Entry) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.closed {
+		slog.Error("Audit entry recorded after the audit log was closed", "query", entry.Query)
+		return
+	}
+	l.entries <- entry
+}
+
+// run writes queued entries to every sink until the logger is closed
+func (l *Logger) run() {
+	defer close(l.done)
+
+	for entry := range l.entries {
+		for _, sink := range l.sinks {
+			if err := sink.Write(entry); err != nil {
+				slog.Error("Failed to write audit entry", "sink", sink.name, "query", entry.Query, "error", err)
+			}
+		}
+	}
+}
+
+// Close writes the queued entries and closes the sinks
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	close(l.entries)
+	l.mu.Unlock()
+
+	<-l.done
+
+	var errs []error
+	for _, sink := range l.sinks {
+		if err := sink.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close audit sink %s: %w", sink.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,200 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/logging"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+// rowsExecutor returns one row per "rows" parameter, or a client error when it is missing
+type rowsExecutor struct {
+	query.QueryExecutor
+}
+
+func (e *rowsExecutor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	count, ok := params["rows"].(int)
+	if !ok {
+		return nil, query.NewClientError("required parameter 'rows' is missing")
+	}
+	return make([]map[string]interface{}, count), nil
+}
+
+func (e *rowsExecutor) BeginSnapshot(ctx context.Context) (query.Snapshot, error) {
+	return rowsSnapshot{e}, nil
+}
+
+func (e *rowsExecutor) Close() error {
+	return nil
+}
+
+// rowsSnapshot executes queries like the executor it was started from
+type rowsSnapshot struct {
+	executor *rowsExecutor
+}
+
+func (s rowsSnapshot) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	return s.executor.Execute(ctx, queryConfig, params)
+}
+
+func (s rowsSnapshot) Close() error {
+	return nil
+}
+
+// readEntries returns the entries of a JSON-lines audit file
+func readEntries(t *testing.T, path string) []Entry {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open audit file: %v", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("failed to parse audit line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestExecutor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.log")
+	logger, err := New(config.AuditConfig{
+		Enabled:  true,
+		Identity: []string{"sub"},
+		Sinks:    []config.AuditSinkConfig{{Type: "file", Path: path}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create audit log: %v", err)
+	}
+	executor := logger.NewExecutor(&rowsExecutor{})
+
+	audited := config.Query{
+		Name:   "user_orders",
+		Audit:  true,
+		Params: []config.QueryParam{{Name: "rows", Type: "int"}, {Name: "card", Type: "string", Sensitive: true}},
+	}
+	ctx := middleware.ContextWithParams(context.Background(), map[string]interface{}{"sub": "user-1", "tenant": "acme"})
+	ctx = logging.WithRequestID(ctx, "req-1")
+
+	executor.Execute(ctx, audited, map[string]interface{}{"rows": 3, "card": "4111"})
+	executor.Execute(ctx, audited, map[string]interface{}{"card": "4111"})
+	executor.Execute(ctx, config.Query{Name: "not_audited"}, map[string]interface{}{"rows": 1})
+
+	snapshot, _ := executor.BeginSnapshot(ctx)
+	snapshot.Execute(ctx, audited, map[string]interface{}{"rows": 1})
+
+	if err := executor.Close(); err != nil {
+		t.Fatalf("failed to close executor: %v", err)
+	}
+
+	entries := readEntries(t, path)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 audit entries, got %d: %+v", len(entries), entries)
+	}
+
+	first := entries[0]
+	if first.Query != "user_orders" || first.RequestID != "req-1" || first.Rows != 3 || first.Outcome != OutcomeSuccess {
+		t.Errorf("unexpected entry: %+v", first)
+	}
+	if len(first.Identity) != 1 || first.Identity["sub"] != "user-1" {
+		t.Errorf("expected identity to contain only sub, got %v", first.Identity)
+	}
+	if first.Params["card"] != "[REDACTED]" {
+		t.Errorf("expected sensitive param to be redacted, got %v", first.Params["card"])
+	}
+	if first.Time.IsZero() {
+		t.Error("expected entry time to be set")
+	}
+
+	if entries[1].Outcome != OutcomeClientError || entries[1].Error == "" {
+		t.Errorf("expected client error entry, got %+v", entries[1])
+	}
+	if entries[2].Rows != 1 {
+		t.Errorf("expected snapshot execution to be recorded, got %+v", entries[2])
+	}
+}
+
+func TestExecutor_AllQueries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	logger, err := New(config.AuditConfig{
+		Enabled:    true,
+		AllQueries: true,
+		Sinks:      []config.AuditSinkConfig{{Type: "file", Path: path}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create audit log: %v", err)
+	}
+	executor := logger.NewExecutor(&rowsExecutor{})
+
+	ctx := middleware.ContextWithParams(context.Background(), map[string]interface{}{"sub": "user-1", "tenant": "acme"})
+	executor.Execute(ctx, config.Query{Name: "list_products"}, map[string]interface{}{"rows": 2})
+	executor.Close()
+
+	entries := readEntries(t, path)
+	if len(entries) != 1 || entries[0].Query != "list_products" {
+		t.Fatalf("expected one entry for list_products, got %+v", entries)
+	}
+	if len(entries[0].Identity) != 2 {
+		t.Errorf("expected all middleware params as identity, got %v", entries[0].Identity)
+	}
+
+	// Entries recorded after closing are discarded
+	executor.Execute(ctx, config.Query{Name: "list_products"}, map[string]interface{}{"rows": 2})
+	if entries := readEntries(t, path); len(entries) != 1 {
+		t.Errorf("expected no entries after close, got %d", len(entries))
+	}
+}
+
+func TestFileSink_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := newFileSink(path, 200, 2)
+	if err != nil {
+		t.Fatalf("failed to create file sink: %v", err)
+	}
+
+	// Each entry is roughly 100 bytes, so every second entry starts a new file
+	for i := 0; i < 8; i++ {
+		if err := sink.Write(Entry{Query: "list_products", Rows: i, Outcome: OutcomeSuccess}); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("expected %s to exist: %v", name, err)
+		}
+		if info.Size() > 200 {
+			t.Errorf("expected %s to be rotated at 200 bytes, got %d", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only 2 backups to be kept, got %v", err)
+	}
+
+	// The newest entries are in the current file, the older ones in the backups
+	current := readEntries(t, path)
+	if len(current) == 0 || current[len(current)-1].Rows != 7 {
+		t.Errorf("expected the last entry in the current file, got %+v", current)
+	}
+	backup := readEntries(t, path+".1")
+	if len(backup) == 0 || backup[len(backup)-1].Rows >= current[0].Rows {
+		t.Errorf("expected older entries in the first backup, got %+v", backup)
+	}
+}
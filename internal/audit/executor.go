@@ -0,0 +1,118 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/logging"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+// Executor records the executions of audited queries, including executions inside
+// snapshots and executions served from the result cache. It wraps another QueryExecutor;
+// all other methods are passed through.
+type Executor struct {
+	query.QueryExecutor
+
+	logger *Logger
+}
+
+// NewExecutor wraps an executor with the audit log
+func (l *Logger) NewExecutor(next query.QueryExecutor) *Executor {
+	return &Executor{QueryExecutor: next, logger: l}
+}
+
+// Execute runs the query and records its execution if the query is audited
+func (e *Executor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	start := time.Now()
+	rows, err := e.QueryExecutor.Execute(ctx, queryConfig, params)
+	e.logger.record(ctx, queryConfig, params, start, len(rows), err)
+	return rows, err
+}
+
+// Stream runs the query, passing its rows on as they are read, and records its execution if
+// the query is audited
+func (e *Executor) Stream(ctx context.Context, queryConfig config.Query, params map[string]interface{}, columns func([]string) error, row func(map[string]interface{}) error) (int, error) {
+	start := time.Now()
+	count, err := query.Stream(ctx, e.QueryExecutor, queryConfig, params, columns, row)
+	e.logger.record(ctx, queryConfig, params, start, count, err)
+	return count, err
+}
+
+// BeginSnapshot starts a snapshot whose executions are recorded
+func (e *Executor) BeginSnapshot(ctx context.Context) (query.Snapshot, error) {
+	snapshot, err := e.QueryExecutor.BeginSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshotExecutor{Snapshot: snapshot, logger: e.logger}, nil
+}
+
+// Close writes the remaining audit entries, then closes the wrapped executor
+func (e *Executor) Close() error {
+	if err := e.logger.Close(); err != nil {
+		e.QueryExecutor.Close()
+		return err
+	}
+	return e.QueryExecutor.Close()
+}
+
+// snapshotExecutor records the executions of a snapshot
+type snapshotExecutor struct {
+	query.Snapshot
+
+	logger *Logger
+}
+
+// Execute runs the query inside the snapshot and records its execution if the query is audited
+func (s *snapshotExecutor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	start := time.Now()
+	rows, err := s.Snapshot.Execute(ctx, queryConfig, params)
+	s.logger.record(ctx, queryConfig, params, start, len(rows), err)
+	return rows, err
+}
+
+// record builds the entry of an execution. The caller is identified by the middleware
+// parameters of the request context.
+func (l *Logger) record(ctx context.Context, queryConfig config.Query, params map[string]interface{}, start time.Time, rows int, err error) {
+	if !l.Audits(queryConfig) {
+		return
+	}
+
+	entry := Entry{
+		Time:       start.UTC(),
+		RequestID:  logging.RequestID(ctx),
+		Query:      queryConfig.Name,
+		Identity:   logging.Redact(queryConfig, l.identity(ctx)),
+		Params:     logging.Redact(queryConfig, params),
+		Rows:       rows,
+		Outcome:    OutcomeSuccess,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		entry.Outcome = OutcomeError
+		if query.IsClientError(err) {
+			entry.Outcome = OutcomeClientError
+		}
+		entry.Error = err.Error()
+	}
+	l.Record(entry)
+}
+
+// identity returns the configured middleware parameters of the request, or all of them
+func (l *Logger) identity(ctx context.Context) map[string]interface{} {
+	middlewareParams := middleware.ParamsFromContext(ctx)
+	if len(l.auditConfig.Identity) == 0 {
+		return middlewareParams
+	}
+
+	identity := make(map[string]interface{}, len(l.auditConfig.Identity))
+	for _, name := range l.auditConfig.Identity {
+		if value, ok := middlewareParams[name]; ok {
+			identity[name] = value
+		}
+	}
+	return identity
+}
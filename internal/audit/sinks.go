@@ -0,0 +1,262 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/db"
+)
+
+const (
+	// Defaults for file sinks when not set in the server configuration
+	defaultMaxSizeMB  = 100
+	defaultMaxBackups = 5
+
+	// defaultTable is the table postgres sinks insert into when not configured
+	defaultTable = "audit_log"
+
+	// writeTimeout bounds a single insert into the audit table
+	writeTimeout = 10 * time.Second
+)
+
+// newSink creates a sink from its configuration
+func newSink(sinkConfig config.AuditSinkConfig, dbConfig *config.DatabaseConfig) (Sink, error) {
+	switch sinkConfig.Type {
+	case "file":
+		maxSizeMB := sinkConfig.MaxSizeMB
+		if maxSizeMB == 0 {
+			maxSizeMB = defaultMaxSizeMB
+		}
+		maxBackups := sinkConfig.MaxBackups
+		if maxBackups == 0 {
+			maxBackups = defaultMaxBackups
+		}
+		return newFileSink(sinkConfig.Path, int64(maxSizeMB)<<20, maxBackups)
+	case "stdout":
+		return &writerSink{w: os.Stdout}, nil
+	case "postgres":
+		table := sinkConfig.Table
+		if table == "" {
+			table = defaultTable
+		}
+		if sinkConfig.DSN == "" && dbConfig != nil {
+			// The database DSN is read again on reconnect, like for the query connections
+			return newPostgresSink(db.NewConnector(dbConfig), table), nil
+		}
+		connector, err := pq.NewConnector(sinkConfig.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit database: %w", err)
+		}
+		return newPostgresSink(connector, table), nil
+	default:
+		return nil, fmt.Errorf("unsupported sink type: %s", sinkConfig.Type)
+	}
+}
+
+// writerSink writes entries as JSON lines to a writer such as stdout
+type writerSink struct {
+	w io.Writer
+}
+
+func (s *writerSink) Write(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+func (s *writerSink) Close() error {
+	return nil
+}
+
+// fileSink appends entries as JSON lines to a file. When the file would grow beyond
+// maxSize it is renamed to path.1, shifting older files up to path.<maxBackups>.
+type fileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// newFileSink opens the audit file for appending, creating it and its directory if needed
+func newFileSink(path string, maxSize int64, maxBackups int) (*fileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	s := &fileSink{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// open opens the current audit file and records its size
+func (s *fileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open audit file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat audit file: %w", err)
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+func (s *fileSink) Write(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		// A previous rotation failed to reopen the file
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	if s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+// rotate moves the current file to the first backup and starts a new one
+func (s *fileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to close audit file: %w", err)
+	}
+	s.file = nil
+
+	// Renaming over the oldest backup discards it
+	for i := s.maxBackups - 1; i >= 1; i-- {
+		backup := fmt.Sprintf("%s.%d", s.path, i)
+		if _, err := os.Stat(backup); err == nil {
+			if err := os.Rename(backup, fmt.Sprintf("%s.%d", s.path, i+1)); err != nil {
+				return fmt.Errorf("failed to rotate audit file: %w", err)
+			}
+		}
+	}
+	if err := os.Rename(s.path, s.path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate audit file: %w", err)
+	}
+
+	return s.open()
+}
+
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// postgresSink inserts entries into a table through its own connection, so auditing
+// neither competes with queries for pooled connections nor joins their transactions.
+// The table is created on the first write if it does not exist.
+type postgresSink struct {
+	db     *sql.DB
+	table  string
+	insert string
+
+	tableReady bool // only accessed by the writing goroutine
+}
+
+// newPostgresSink opens the audit connection. The database is not contacted until the
+// first entry is written, so the server starts while the database is unavailable.
+func newPostgresSink(connector driver.Connector, table string) *postgresSink {
+	db := sql.OpenDB(connector)
+	db.SetMaxOpenConns(1)
+
+	return &postgresSink{
+		db:    db,
+		table: table,
+		insert: fmt.Sprintf(`INSERT INTO %s (time, request_id, query, identity, params, rows, outcome, error, duration_ms)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`, table),
+	}
+}
+
+func (s *postgresSink) Write(entry Entry) error {
+	identity, err := json.Marshal(entry.Identity)
+	if err != nil {
+		return err
+	}
+	params, err := json.Marshal(entry.Params)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	if !s.tableReady {
+		if err := s.createTable(ctx); err != nil {
+			return err
+		}
+		s.tableReady = true
+	}
+
+	_, err = s.db.ExecContext(ctx, s.insert,
+		entry.Time, entry.RequestID, entry.Query, identity, params,
+		entry.Rows, entry.Outcome, entry.Error, entry.DurationMs)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	return nil
+}
+
+// createTable creates the audit table if it does not exist
+func (s *postgresSink) createTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id BIGSERIAL PRIMARY KEY,
+		time TIMESTAMPTZ NOT NULL,
+		request_id TEXT NOT NULL,
+		query TEXT NOT NULL,
+		identity JSONB NOT NULL,
+		params JSONB NOT NULL,
+		rows INTEGER NOT NULL,
+		outcome TEXT NOT NULL,
+		error TEXT NOT NULL,
+		duration_ms DOUBLE PRECISION NOT NULL
+	)`, s.table))
+	if err != nil {
+		return fmt.Errorf("failed to create audit table %s: %w", s.table, err)
+	}
+	return nil
+}
+
+func (s *postgresSink) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,137 @@
+// Package cache provides an in-memory result cache in front of a query executor
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+// defaultMaxEntries is the cache size of a query when max_entries is not set
+const defaultMaxEntries = 1000
+
+// bypassKey is the context key marking executions that must not be served from the cache
+type bypassKey struct{}
+
+// WithBypass returns a context whose executions skip cache lookups. Their results
+// still refresh the cache.
+func WithBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// Bypassed reports whether cache lookups are skipped for the context. Executions
+// bypassing the cache must not reuse results read by other callers either.
+func Bypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassKey{}).(bool)
+	return bypass
+}
+
+// Executor caches the results of queries that declare a cache. It wraps another
+// QueryExecutor; all other methods, including snapshots, are passed through.
+// Cached rows are shared between callers and must not be modified.
+type Executor struct {
+	query.QueryExecutor
+
+	mu     sync.Mutex
+	caches map[string]*lru // Keyed by query name
+	now    func() time.Time
+}
+
+// NewExecutor wraps an executor with a result cache
+func NewExecutor(next query.QueryExecutor) *Executor {
+	return &Executor{
+		QueryExecutor: next,
+		caches:        make(map[string]*lru),
+		now:           time.Now,
+	}
+}
+
+// Execute returns a cached result if one exists for the query and parameters, and
+// executes the query otherwise. The parameters include middleware parameters, so
+// results are never shared between callers with different middleware values.
+func (e *Executor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	if queryConfig.Cache == nil || queryConfig.Name == "" {
+		return e.QueryExecutor.Execute(ctx, queryConfig, params)
+	}
+
+	key, err := json.Marshal(params)
+	if err != nil {
+		// Parameters that cannot be encoded are left to the executor to reject
+		return e.QueryExecutor.Execute(ctx, queryConfig, params)
+	}
+
+	cache := e.cacheFor(queryConfig)
+	if !Bypassed(ctx) {
+		if rows, ok := cache.get(string(key), e.now()); ok {
+			return rows, nil
+		}
+	}
+
+	generation := cache.currentGeneration()
+	rows, err := e.QueryExecutor.Execute(ctx, queryConfig, params)
+	if err != nil {
+		return nil, err
+	}
+	cache.add(string(key), rows, e.now().Add(queryConfig.Cache.TTL), generation)
+
+	return rows, nil
+}
+
+// Stream runs the query, passing its rows on as they are read. Streamed results are
+// neither served from nor stored in the cache.
+func (e *Executor) Stream(ctx context.Context, queryConfig config.Query, params map[string]interface{}, columns func([]string) error, row func(map[string]interface{}) error) (int, error) {
+	return query.Stream(ctx, e.QueryExecutor, queryConfig, params, columns, row)
+}
+
+// Invalidate removes all cached results of a query
+func (e *Executor) Invalidate(queryName string) {
+	e.mu.Lock()
+	cache, exists := e.caches[queryName]
+	e.mu.Unlock()
+
+	if exists {
+		cache.invalidate()
+	}
+}
+
+// Remove drops the cache of a query, e.g. after its definition changed, so that a new
+// cache with the current settings is created on its next execution
+func (e *Executor) Remove(queryName string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.caches, queryName)
+}
+
+// Stats returns the statistics of every query cache, keyed by query name
+func (e *Executor) Stats() map[string]Stats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	stats := make(map[string]Stats, len(e.caches))
+	for name, cache := range e.caches {
+		stats[name] = cache.snapshot()
+	}
+	return stats
+}
+
+// cacheFor returns the cache of a query, creating it on first use
+func (e *Executor) cacheFor(queryConfig config.Query) *lru {
+	maxEntries := queryConfig.Cache.MaxEntries
+	if maxEntries == 0 {
+		maxEntries = defaultMaxEntries
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cache, exists := e.caches[queryConfig.Name]
+	if !exists {
+		cache = newLRU(maxEntries)
+		e.caches[queryConfig.Name] = cache
+	}
+	return cache
+}
@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+// countingExecutor returns the number of executions so far as its result
+type countingExecutor struct {
+	query.QueryExecutor
+	calls int
+	fail  bool
+}
+
+func (e *countingExecutor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	e.calls++
+	if e.fail {
+		return nil, errors.New("database error")
+	}
+	return []map[string]interface{}{{"call": e.calls}}, nil
+}
+
+func TestExecutor(t *testing.T) {
+	cached := config.Query{Name: "products", SQL: "SELECT", Cache: &config.QueryCache{TTL: time.Minute, MaxEntries: 2}}
+	params := map[string]interface{}{"category": "books", "tenant_id": "t1"}
+
+	newExecutor := func() (*Executor, *countingExecutor, *time.Time) {
+		next := &countingExecutor{}
+		now := time.Now()
+		executor := NewExecutor(next)
+		executor.now = func() time.Time { return now }
+		return executor, next, &now
+	}
+
+	t.Run("ServesCachedResultsUntilExpired", func(t *testing.T) {
+		executor, next, now := newExecutor()
+
+		executor.Execute(context.Background(), cached, params)
+		rows, _ := executor.Execute(context.Background(), cached, params)
+		if next.calls != 1 || rows[0]["call"] != 1 {
+			t.Errorf("expected cached result, got %v after %d calls", rows, next.calls)
+		}
+
+		*now = now.Add(time.Minute)
+		rows, _ = executor.Execute(context.Background(), cached, params)
+		if next.calls != 2 || rows[0]["call"] != 2 {
+			t.Errorf("expected fresh result after expiry, got %v after %d calls", rows, next.calls)
+		}
+
+		stats := executor.Stats()["products"]
+		if stats.Hits != 1 || stats.Misses != 2 || stats.Entries != 1 {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+	})
+
+	t.Run("SeparatesMiddlewareParams", func(t *testing.T) {
+		executor, next, _ := newExecutor()
+
+		executor.Execute(context.Background(), cached, params)
+		executor.Execute(context.Background(), cached, map[string]interface{}{"category": "books", "tenant_id": "t2"})
+		if next.calls != 2 {
+			t.Errorf("expected tenants not to share entries, got %d calls", next.calls)
+		}
+	})
+
+	t.Run("BypassRefreshesCache", func(t *testing.T) {
+		executor, next, _ := newExecutor()
+
+		executor.Execute(context.Background(), cached, params)
+		executor.Execute(WithBypass(context.Background()), cached, params)
+		rows, _ := executor.Execute(context.Background(), cached, params)
+		if next.calls != 2 || rows[0]["call"] != 2 {
+			t.Errorf("expected bypass to refresh the cache, got %v after %d calls", rows, next.calls)
+		}
+	})
+
+	t.Run("EvictsLeastRecentlyUsed", func(t *testing.T) {
+		executor, next, _ := newExecutor()
+
+		for _, id := range []int{1, 2, 1, 3, 1, 2} {
+			executor.Execute(context.Background(), cached, map[string]interface{}{"id": id})
+		}
+		// 1, 2 and 3 miss; 1 hits twice; 2 was evicted by 3 and misses again
+		if next.calls != 4 {
+			t.Errorf("expected 4 executions, got %d", next.calls)
+		}
+		if stats := executor.Stats()["products"]; stats.Evictions != 2 || stats.Entries != 2 {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+	})
+
+	t.Run("DoesNotCacheErrors", func(t *testing.T) {
+		executor, next, _ := newExecutor()
+		next.fail = true
+
+		executor.Execute(context.Background(), cached, params)
+		executor.Execute(context.Background(), cached, params)
+		if next.calls != 2 {
+			t.Errorf("expected errors not to be cached, got %d calls", next.calls)
+		}
+	})
+
+	t.Run("Invalidate", func(t *testing.T) {
+		executor, next, _ := newExecutor()
+
+		executor.Execute(context.Background(), cached, params)
+		executor.Invalidate("products")
+		rows, _ := executor.Execute(context.Background(), cached, params)
+		if next.calls != 2 || rows[0]["call"] != 2 {
+			t.Errorf("expected fresh result after invalidation, got %v after %d calls", rows, next.calls)
+		}
+		if stats := executor.Stats()["products"]; stats.Invalidations != 1 || stats.Entries != 1 {
+			t.Errorf("unexpected stats: %+v", stats)
+		}
+	})
+
+	t.Run("DiscardsResultsComputedBeforeInvalidation", func(t *testing.T) {
+		cache := newLRU(10)
+		generation := cache.currentGeneration()
+		cache.invalidate()
+		cache.add("key", []map[string]interface{}{}, time.Now().Add(time.Minute), generation)
+		if _, ok := cache.get("key", time.Now()); ok {
+			t.Error("expected stale result to be discarded")
+		}
+	})
+
+	t.Run("PassesThroughUncachedQueries", func(t *testing.T) {
+		executor, next, _ := newExecutor()
+		uncached := config.Query{Name: "users", SQL: "SELECT"}
+
+		executor.Execute(context.Background(), uncached, params)
+		executor.Execute(context.Background(), uncached, params)
+		if next.calls != 2 || len(executor.Stats()) != 0 {
+			t.Errorf("expected no caching, got %d calls and stats %v", next.calls, executor.Stats())
+		}
+	})
+}
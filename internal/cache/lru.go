@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Stats reports the usage of a query's result cache
+type Stats struct {
+	Entries       int   `json:"entries"`
+	Hits          int64 `json:"hits"`
+	Misses        int64 `json:"misses"`
+	Evictions     int64 `json:"evictions"`     // Entries removed to make room for new ones
+	Invalidations int64 `json:"invalidations"` // Times the cache was cleared by a notification
+}
+
+// entry is a cached result
+type entry struct {
+	key       string
+	rows      []map[string]interface{}
+	expiresAt time.Time
+}
+
+// lru is a size-bounded cache evicting the least recently used entries
+type lru struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List // Front is the most recently used
+	generation uint64     // Incremented on every invalidation
+	stats      Stats
+}
+
+// newLRU creates a cache holding at most maxEntries entries
+func newLRU(maxEntries int) *lru {
+	return &lru{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// get returns the unexpired result stored under key
+func (c *lru) get(key string, now time.Time) ([]map[string]interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, exists := c.entries[key]
+	if !exists {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	e := element.Value.(*entry)
+	if !now.Before(e.expiresAt) {
+		c.removeElement(element)
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	c.stats.Hits++
+	return e.rows, true
+}
+
+// currentGeneration returns the generation to pass to add for a result about to be computed
+func (c *lru) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// add stores a result under key, evicting the least recently used entry when full. Results
+// computed before an invalidation (an older generation) are discarded, as they may be stale.
+func (c *lru) add(key string, rows []map[string]interface{}, expiresAt time.Time, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	if element, exists := c.entries[key]; exists {
+		element.Value = &entry{key: key, rows: rows, expiresAt: expiresAt}
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&entry{key: key, rows: rows, expiresAt: expiresAt})
+	for c.order.Len() > c.maxEntries {
+		c.removeElement(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+// invalidate removes all entries
+func (c *lru) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+	c.generation++
+	c.stats.Invalidations++
+}
+
+// snapshot returns the current statistics
+func (c *lru) snapshot() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = c.order.Len()
+	return stats
+}
+
+// removeElement removes an entry; c.mu must be held
+func (c *lru) removeElement(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*entry).key)
+}
@@ -0,0 +1,63 @@
+// Package coalesce shares one execution between identical concurrent query calls
+package coalesce
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/shogotsuneto/simple-query-server/internal/cache"
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+	"golang.org/x/sync/singleflight"
+)
+
+// Executor coalesces concurrent executions of queries that enable coalescing and are
+// called with identical parameters, including middleware parameters. It wraps another
+// QueryExecutor; all other methods are passed through. Shared rows must not be modified.
+type Executor struct {
+	query.QueryExecutor
+
+	group singleflight.Group
+}
+
+// NewExecutor wraps an executor with request coalescing
+func NewExecutor(next query.QueryExecutor) *Executor {
+	return &Executor{QueryExecutor: next}
+}
+
+// Execute joins an identical execution that is already in flight, or starts one.
+// The shared execution is not cancelled when a single caller goes away; each caller
+// still returns as soon as its own context is done. Executions bypassing the result
+// cache run on their own, as a shared one may have started before the data changed.
+func (e *Executor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	if !queryConfig.Coalesce || queryConfig.Name == "" || cache.Bypassed(ctx) {
+		return e.QueryExecutor.Execute(ctx, queryConfig, params)
+	}
+
+	encodedParams, err := json.Marshal(params)
+	if err != nil {
+		// Parameters that cannot be encoded are left to the executor to reject
+		return e.QueryExecutor.Execute(ctx, queryConfig, params)
+	}
+	key := queryConfig.Name + "\x00" + string(encodedParams)
+
+	results := e.group.DoChan(key, func() (interface{}, error) {
+		return e.QueryExecutor.Execute(context.WithoutCancel(ctx), queryConfig, params)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-results:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return result.Val.([]map[string]interface{}), nil
+	}
+}
+
+// Stream runs the query, passing its rows on as they are read. Streamed executions are
+// not shared between callers.
+func (e *Executor) Stream(ctx context.Context, queryConfig config.Query, params map[string]interface{}, columns func([]string) error, row func(map[string]interface{}) error) (int, error) {
+	return query.Stream(ctx, e.QueryExecutor, queryConfig, params, columns, row)
+}
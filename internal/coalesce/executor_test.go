@@ -0,0 +1,110 @@
+package coalesce
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/cache"
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+// gatedExecutor blocks every execution until the gate is closed
+type gatedExecutor struct {
+	query.QueryExecutor
+	gate  chan struct{}
+	calls atomic.Int64
+}
+
+func (e *gatedExecutor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	e.calls.Add(1)
+	select {
+	case <-e.gate:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return []map[string]interface{}{{"tenant_id": params["tenant_id"]}}, nil
+}
+
+// executeConcurrently runs the query once per parameter set and waits until all calls have
+// reached the wrapped executor or joined an execution before releasing the gate
+func executeConcurrently(t *testing.T, ctx context.Context, queryConfig config.Query, paramSets []map[string]interface{}, expectedExecutions int64) int64 {
+	t.Helper()
+	next := &gatedExecutor{gate: make(chan struct{})}
+	executor := NewExecutor(next)
+
+	var wg sync.WaitGroup
+	for _, params := range paramSets {
+		wg.Add(1)
+		go func(params map[string]interface{}) {
+			defer wg.Done()
+			rows, err := executor.Execute(ctx, queryConfig, params)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if rows[0]["tenant_id"] != params["tenant_id"] {
+				t.Errorf("expected result for %v, got %v", params["tenant_id"], rows[0]["tenant_id"])
+			}
+		}(params)
+	}
+
+	// Give the callers time to join in-flight executions
+	deadline := time.Now().Add(time.Second)
+	for next.calls.Load() < expectedExecutions && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(next.gate)
+	wg.Wait()
+
+	return next.calls.Load()
+}
+
+func TestExecutor(t *testing.T) {
+	coalesced := config.Query{Name: "top_products", SQL: "SELECT", Coalesce: true}
+	t1 := map[string]interface{}{"tenant_id": "t1"}
+	t2 := map[string]interface{}{"tenant_id": "t2"}
+
+	t.Run("SharesIdenticalCalls", func(t *testing.T) {
+		if calls := executeConcurrently(t, context.Background(), coalesced, []map[string]interface{}{t1, t1, t1, t1}, 1); calls != 1 {
+			t.Errorf("expected 1 execution, got %d", calls)
+		}
+	})
+
+	t.Run("SeparatesDifferentParams", func(t *testing.T) {
+		if calls := executeConcurrently(t, context.Background(), coalesced, []map[string]interface{}{t1, t2, t1, t2}, 2); calls != 2 {
+			t.Errorf("expected 2 executions, got %d", calls)
+		}
+	})
+
+	t.Run("PassesThroughWhenDisabled", func(t *testing.T) {
+		fresh := config.Query{Name: "top_products", SQL: "SELECT"}
+		if calls := executeConcurrently(t, context.Background(), fresh, []map[string]interface{}{t1, t1, t1}, 3); calls != 3 {
+			t.Errorf("expected 3 executions, got %d", calls)
+		}
+	})
+
+	t.Run("RunsCacheBypassingCallsAlone", func(t *testing.T) {
+		ctx := cache.WithBypass(context.Background())
+		if calls := executeConcurrently(t, ctx, coalesced, []map[string]interface{}{t1, t1, t1}, 3); calls != 3 {
+			t.Errorf("expected 3 executions, got %d", calls)
+		}
+	})
+
+	t.Run("CallerCancellation", func(t *testing.T) {
+		next := &gatedExecutor{gate: make(chan struct{})}
+		executor := NewExecutor(next)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := executor.Execute(ctx, coalesced, t1); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+		close(next.gate)
+	})
+}
@@ -0,0 +1,211 @@
+// Package compression compresses HTTP responses according to the Accept-Encoding request header
+package compression
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+)
+
+// Defaults when not set in the server configuration
+const defaultMinSize = 1024
+
+var defaultAlgorithms = []string{"zstd", "br", "gzip"}
+
+// encoder is a compressing writer that can flush partial output for streaming responses
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// newEncoder creates an encoder for a content coding
+func newEncoder(encoding string, w io.Writer) encoder {
+	switch encoding {
+	case "zstd":
+		// Errors only occur for invalid options
+		zstdEncoder, _ := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		return zstdEncoder
+	case "br":
+		return brotli.NewWriterLevel(w, brotli.DefaultCompression)
+	default:
+		return gzip.NewWriter(w)
+	}
+}
+
+// Handler compresses responses of at least the configured size with the preferred encoding
+// accepted by the client. Streaming responses are compressed as they are flushed.
+// WebSocket upgrades are passed through untouched.
+func Handler(compressionConfig config.CompressionConfig, next http.Handler) http.Handler {
+	minSize := compressionConfig.MinSize
+	if minSize == 0 {
+		minSize = defaultMinSize
+	}
+	algorithms := compressionConfig.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = defaultAlgorithms
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiate(r.Header.Get("Accept-Encoding"), algorithms)
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &responseWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiate selects the supported encoding with the highest quality in the Accept-Encoding
+// header, preferring earlier algorithms on ties. It returns "" if none is acceptable.
+func negotiate(acceptEncoding string, algorithms []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		qualities[strings.ToLower(strings.TrimSpace(coding))] = quality
+	}
+
+	best, bestQuality := "", 0.0
+	for _, algorithm := range algorithms {
+		quality, exists := qualities[algorithm]
+		if !exists {
+			quality, exists = qualities["*"]
+		}
+		if exists && quality > bestQuality {
+			best, bestQuality = algorithm, quality
+		}
+	}
+	return best
+}
+
+// responseWriter buffers the start of a response until it is known whether the response
+// is large enough to be compressed
+type responseWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	statusCode int
+	buffer     []byte
+	decided    bool
+	encoder    encoder // nil unless the response is compressed
+}
+
+// WriteHeader records the status code; headers are sent once compression is decided
+func (w *responseWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+}
+
+// Write buffers the body until the minimum size is reached, then compresses it
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	if !w.decided {
+		w.buffer = append(w.buffer, p...)
+		if len(w.buffer) >= w.minSize {
+			if err := w.decide(true); err != nil {
+				return 0, err
+			}
+		}
+		return len(p), nil
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush sends buffered output. Flushing indicates a streaming response, which is compressed
+// regardless of its size so far.
+func (w *responseWriter) Flush() {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	if !w.decided {
+		w.decide(true)
+	}
+	if w.encoder != nil {
+		w.encoder.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying response writer for http.ResponseController
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide sends the headers and the buffered body, compressed if requested and applicable
+func (w *responseWriter) decide(compress bool) error {
+	w.decided = true
+	header := w.Header()
+
+	// Responses without a body and already encoded responses are left untouched
+	if w.statusCode < http.StatusOK || w.statusCode == http.StatusNoContent || w.statusCode == http.StatusNotModified || header.Get("Content-Encoding") != "" {
+		compress = false
+	}
+
+	if compress {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		// The compressed representation differs byte for byte, so strong validators become weak
+		if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			header.Set("ETag", "W/"+etag)
+		}
+		w.ResponseWriter.WriteHeader(w.statusCode)
+		w.encoder = newEncoder(w.encoding, w.ResponseWriter)
+		_, err := w.encoder.Write(w.buffer)
+		w.buffer = nil
+		return err
+	}
+
+	w.ResponseWriter.WriteHeader(w.statusCode)
+	_, err := w.ResponseWriter.Write(w.buffer)
+	w.buffer = nil
+	return err
+}
+
+// close completes the response: small responses are sent uncompressed, and the encoder
+// of compressed responses is closed
+func (w *responseWriter) close() {
+	if !w.decided {
+		if w.statusCode == 0 {
+			// Nothing was written; the server sends its default response
+			return
+		}
+		w.decide(false)
+		return
+	}
+	if w.encoder != nil {
+		w.encoder.Close()
+	}
+}
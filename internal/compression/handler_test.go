@@ -0,0 +1,192 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+)
+
+// decode decompresses a response body according to its Content-Encoding
+func decode(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+	var reader io.Reader
+	switch encoding {
+	case "gzip":
+		gzipReader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("invalid gzip body: %v", err)
+		}
+		reader = gzipReader
+	case "zstd":
+		zstdReader, err := zstd.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("invalid zstd body: %v", err)
+		}
+		defer zstdReader.Close()
+		reader = zstdReader
+	case "br":
+		reader = brotli.NewReader(bytes.NewReader(body))
+	default:
+		return string(body)
+	}
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("failed to decode %s body: %v", encoding, err)
+	}
+	return string(decoded)
+}
+
+func TestNegotiate(t *testing.T) {
+	algorithms := []string{"zstd", "br", "gzip"}
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		expected       string
+	}{
+		{"NoHeader", "", ""},
+		{"Single", "gzip", "gzip"},
+		{"ConfiguredOrderBreaksTies", "gzip, br, zstd", "zstd"},
+		{"QualityWins", "zstd;q=0.5, gzip", "gzip"},
+		{"Rejected", "zstd;q=0, br;q=0, gzip;q=0", ""},
+		{"Wildcard", "*", "zstd"},
+		{"WildcardWithExclusion", "zstd;q=0, *;q=0.8", "br"},
+		{"Unsupported", "deflate, identity", ""},
+		{"CaseInsensitive", "GZIP", "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if encoding := negotiate(tt.acceptEncoding, algorithms); encoding != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, encoding)
+			}
+		})
+	}
+
+	if encoding := negotiate("gzip, zstd", []string{"gzip"}); encoding != "gzip" {
+		t.Errorf("expected only configured algorithms to be used, got %q", encoding)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	large := strings.Repeat(`{"id":1,"name":"Alice"}`, 100)
+	respond := func(body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", `"abc"`)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(body))
+		})
+	}
+	serve := func(handler http.Handler, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/query/users", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		Handler(config.CompressionConfig{Enabled: true}, handler).ServeHTTP(w, req)
+		return w
+	}
+
+	for _, encoding := range []string{"gzip", "zstd", "br"} {
+		t.Run("Compresses_"+encoding, func(t *testing.T) {
+			w := serve(respond(large), map[string]string{"Accept-Encoding": encoding})
+			if w.Header().Get("Content-Encoding") != encoding {
+				t.Fatalf("expected %s encoding, got %q", encoding, w.Header().Get("Content-Encoding"))
+			}
+			if w.Body.Len() >= len(large) {
+				t.Errorf("expected compressed body, got %d bytes", w.Body.Len())
+			}
+			if body := decode(t, encoding, w.Body.Bytes()); body != large {
+				t.Errorf("unexpected decoded body %q", body)
+			}
+			if etag := w.Header().Get("ETag"); etag != `W/"abc"` {
+				t.Errorf("expected weak ETag, got %q", etag)
+			}
+			if w.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("expected Vary header, got %q", w.Header().Get("Vary"))
+			}
+		})
+	}
+
+	t.Run("SkipsSmallResponses", func(t *testing.T) {
+		w := serve(respond(`{"rows":[]}`), map[string]string{"Accept-Encoding": "gzip"})
+		if w.Header().Get("Content-Encoding") != "" || w.Body.String() != `{"rows":[]}` {
+			t.Errorf("expected uncompressed body, got %q encoded as %q", w.Body.String(), w.Header().Get("Content-Encoding"))
+		}
+		if etag := w.Header().Get("ETag"); etag != `"abc"` {
+			t.Errorf("expected strong ETag, got %q", etag)
+		}
+	})
+
+	t.Run("SkipsWithoutAcceptEncoding", func(t *testing.T) {
+		w := serve(respond(large), nil)
+		if w.Header().Get("Content-Encoding") != "" || w.Body.String() != large {
+			t.Errorf("expected uncompressed body, got encoding %q", w.Header().Get("Content-Encoding"))
+		}
+	})
+
+	t.Run("SkipsNotModified", func(t *testing.T) {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotModified)
+		})
+		w := serve(handler, map[string]string{"Accept-Encoding": "gzip"})
+		if w.Code != http.StatusNotModified || w.Header().Get("Content-Encoding") != "" || w.Body.Len() != 0 {
+			t.Errorf("expected empty 304, got %d with encoding %q", w.Code, w.Header().Get("Content-Encoding"))
+		}
+	})
+
+	t.Run("SkipsUpgrades", func(t *testing.T) {
+		w := serve(respond(large), map[string]string{"Accept-Encoding": "gzip", "Upgrade": "websocket"})
+		if w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("expected upgrade to pass through, got encoding %q", w.Header().Get("Content-Encoding"))
+		}
+	})
+
+	t.Run("CompressesFlushedStreams", func(t *testing.T) {
+		flushed := make(chan string, 1)
+		var recorder *httptest.ResponseRecorder
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("event: result\ndata: {}\n\n"))
+			w.(http.Flusher).Flush()
+			// The first event is readable before the stream ends
+			flushed <- decodePartialGzip(t, recorder.Body.Bytes())
+			w.Write([]byte("event: result\ndata: {\"rows\":[]}\n\n"))
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/subscribe/users", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		recorder = httptest.NewRecorder()
+		Handler(config.CompressionConfig{Enabled: true}, handler).ServeHTTP(recorder, req)
+
+		if first := <-flushed; first != "event: result\ndata: {}\n\n" {
+			t.Errorf("expected first event after flush, got %q", first)
+		}
+		if !recorder.Flushed || recorder.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("expected flushed gzip stream, got encoding %q", recorder.Header().Get("Content-Encoding"))
+		}
+		if body := decode(t, "gzip", recorder.Body.Bytes()); body != "event: result\ndata: {}\n\nevent: result\ndata: {\"rows\":[]}\n\n" {
+			t.Errorf("unexpected decoded stream %q", body)
+		}
+	})
+}
+
+// decodePartialGzip decompresses the complete blocks of an unfinished gzip stream
+func decodePartialGzip(t *testing.T, body []byte) string {
+	t.Helper()
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("invalid gzip stream: %v", err)
+	}
+	decoded, _ := io.ReadAll(reader)
+	return string(decoded)
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envPattern matches ${VAR} and ${VAR:-default} references, and $${ escaping a literal ${
+var envPattern = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// fileSuffix marks middleware config keys whose value is read from the named file
+const fileSuffix = "_file"
+
+// decodeYAML parses a YAML document, expands environment variable references in its values
+// and decodes it into out, rejecting keys out has no field for. Values are expanded after
+// parsing, so variables cannot change the structure of the document, and unquoted values are
+// typed after expansion (e.g. "max_items: ${MAX_ITEMS}" decodes into an int). Errors start
+// with the path and, where known, the line and column. The parsed document is returned to
+// locate values in later errors.
+func decodeYAML(path string, data []byte, out interface{}) (*yaml.Node, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if document.Kind == 0 {
+		// Empty document
+		return &document, nil
+	}
+	if err := expandNode(path, &document); err != nil {
+		return nil, err
+	}
+	if err := checkKnownFields(path, &document, reflect.TypeOf(out)); err != nil {
+		return nil, err
+	}
+	if err := document.Decode(out); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &document, nil
+}
+
+// expandNode expands environment variable references in the scalar values below a node.
+// Mapping keys are left as written.
+func expandNode(path string, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		expanded, err := expandEnv(node.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", position(path, node), err)
+		}
+		if expanded != node.Value {
+			node.Value = expanded
+			if node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+				// Resolve the type of plain values from the expanded value
+				node.Tag = ""
+			}
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := expandNode(path, node.Content[i]); err != nil {
+				return err
+			}
+		}
+	default:
+		for _, child := range node.Content {
+			if err := expandNode(path, child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// expandEnv replaces ${VAR} with the value of the environment variable VAR, which must be
+// set, and ${VAR:-default} with the value of VAR or the default when VAR is unset or empty
+func expandEnv(value string) (string, error) {
+	var missing []string
+	expanded := envPattern.ReplaceAllStringFunc(value, func(reference string) string {
+		if reference == "$${" {
+			return "${"
+		}
+		match := envPattern.FindStringSubmatch(reference)
+		name, hasDefault, defaultValue := match[1], match[2] != "", match[3]
+
+		envValue, set := os.LookupEnv(name)
+		if hasDefault && envValue == "" {
+			return defaultValue
+		}
+		if !set {
+			missing = append(missing, name)
+		}
+		return envValue
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// readSecretFile returns the contents of a file holding a secret, such as a mounted
+// Kubernetes secret, without surrounding whitespace
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// resolveSecret sets a value from its file variant, if any. Only one of them may be set.
+func resolveSecret(name string, value *string, file string) error {
+	if file == "" {
+		return nil
+	}
+	if *value != "" {
+		return fmt.Errorf("%s and %s%s must not both be set", name, name, fileSuffix)
+	}
+	secret, err := readSecretFile(file)
+	if err != nil {
+		return fmt.Errorf("%s%s: %w", name, fileSuffix, err)
+	}
+	*value = secret
+	return nil
+}
+
+// resolveSecretKeys replaces the "<key>_file" entries of a middleware config with "<key>"
+// entries holding the contents of the files
+func resolveSecretKeys(configMap map[string]interface{}) error {
+	keys := make([]string, 0, len(configMap))
+	for key := range configMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name, isFile := strings.CutSuffix(key, fileSuffix)
+		file, isString := configMap[key].(string)
+		if !isFile || !isString || name == "" {
+			continue
+		}
+		if _, exists := configMap[name]; exists {
+			return fmt.Errorf("%s and %s must not both be set", name, key)
+		}
+		secret, err := readSecretFile(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		configMap[name] = secret
+		delete(configMap, key)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("DB_HOST", "db.internal")
+	t.Setenv("EMPTY", "")
+
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+		errorMsg string
+	}{
+		{"NoReferences", "postgres://localhost/app", "postgres://localhost/app", ""},
+		{"Set", "postgres://${DB_HOST}/app", "postgres://db.internal/app", ""},
+		{"DefaultUnused", "${DB_HOST:-localhost}", "db.internal", ""},
+		{"DefaultForUnset", "${DB_PORT:-5432}", "5432", ""},
+		{"DefaultForEmpty", "${EMPTY:-fallback}", "fallback", ""},
+		{"EmptyDefault", "${DB_PORT:-}", "", ""},
+		{"SetEmpty", "${EMPTY}", "", ""},
+		{"Escaped", "$${DB_HOST}", "${DB_HOST}", ""},
+		{"Unset", "${DB_PORT}/${DB_NAME}", "", "environment variable DB_PORT, DB_NAME is not set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expanded, err := expandEnv(tt.value)
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Fatalf("expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if expanded != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, expanded)
+			}
+		})
+	}
+}
+
+func TestDecodeYAML_Types(t *testing.T) {
+	t.Setenv("MAX_SIZE", "10")
+	t.Setenv("ENABLED", "true")
+
+	var config ServerConfig
+	_, err := decodeYAML("server.yaml", []byte(`
+metrics:
+  enabled: ${ENABLED}
+audit:
+  sinks:
+    - type: file
+      path: "/var/log/${UNUSED:-audit}.log"
+      max_size_mb: ${MAX_SIZE}
+`), &config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !config.Metrics.Enabled {
+		t.Error("expected an expanded bool")
+	}
+	sink := config.Audit.Sinks[0]
+	if sink.MaxSizeMB != 10 || sink.Path != "/var/log/audit.log" {
+		t.Errorf("expected expanded values, got %+v", sink)
+	}
+
+	if _, err := decodeYAML("server.yaml", []byte("admin:\n  token: ${MISSING_TOKEN}\n"), &config); err == nil || !strings.Contains(err.Error(), "server.yaml:2:10:") {
+		t.Errorf("expected error with the position of the reference, got %v", err)
+	}
+}
+
+func TestLoadDatabaseConfig_DSNFile(t *testing.T) {
+	dsnPath := writeTempFile(t, "dsn", "postgres://app:initial@db/app\n")
+
+	path := writeTempFile(t, "database.yaml", "type: postgres\ndsn_file: "+dsnPath+"\n")
+	config, err := LoadDatabaseConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.DSN != "postgres://app:initial@db/app" {
+		t.Errorf("expected DSN read from the file, got %q", config.DSN)
+	}
+
+	// The file is read again on every connection
+	if err := os.WriteFile(dsnPath, []byte("postgres://app:rotated@db/app"), 0o644); err != nil {
+		t.Fatalf("failed to rotate DSN file: %v", err)
+	}
+	if dsn, err := config.ReadDSN(); err != nil || dsn != "postgres://app:rotated@db/app" {
+		t.Errorf("expected the rotated DSN, got %q (%v)", dsn, err)
+	}
+
+	both := writeTempFile(t, "database.yaml", "type: postgres\ndsn: postgres://db/app\ndsn_file: "+dsnPath+"\n")
+	if _, err := LoadDatabaseConfig(both); err == nil || !strings.Contains(err.Error(), "must not both be set") {
+		t.Errorf("expected error for dsn and dsn_file, got %v", err)
+	}
+}
+
+func TestLoadServerConfig_SecretFiles(t *testing.T) {
+	tokenPath := writeTempFile(t, "token", "admin-token\n")
+	jwksURLPath := writeTempFile(t, "jwks-url", "https://auth.example.com/jwks?key=abc\n")
+
+	path := writeTempFile(t, "server.yaml", `
+admin:
+  address: localhost:9090
+  token_file: `+tokenPath+`
+middleware:
+  - type: bearer-jwks
+    config:
+      jwks_url_file: `+jwksURLPath+`
+`)
+	config, err := LoadServerConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Admin.Token != "admin-token" {
+		t.Errorf("expected admin token read from the file, got %q", config.Admin.Token)
+	}
+	middlewareConfig := config.Middleware[0].Config
+	if middlewareConfig["jwks_url"] != "https://auth.example.com/jwks?key=abc" {
+		t.Errorf("expected middleware value read from the file, got %v", middlewareConfig)
+	}
+	if _, exists := middlewareConfig["jwks_url_file"]; exists {
+		t.Error("expected the jwks_url_file key to be replaced")
+	}
+
+	missing := writeTempFile(t, "server.yaml", "admin:\n  address: localhost:9090\n  token_file: /nonexistent/token\n")
+	if _, err := LoadServerConfig(missing); err == nil || !strings.Contains(err.Error(), "failed to read secret file") {
+		t.Errorf("expected error for a missing secret file, got %v", err)
+	}
+}
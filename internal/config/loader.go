@@ -2,33 +2,146 @@ package config
 
 import (
 	"fmt"
+	"io/fs"
+	"net/http"
 	"os"
-
-	"gopkg.in/yaml.v3"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 )
 
 // DatabaseConfig represents the database configuration
 type DatabaseConfig struct {
-	Type string `yaml:"type"` // e.g., "postgres", "mysql", "sqlite"
-	DSN  string `yaml:"dsn"`  // Data Source Name
+	Type    string `yaml:"type"`               // e.g., "postgres", "mysql", "sqlite"
+	DSN     string `yaml:"dsn"`                // Data Source Name
+	DSNFile string `yaml:"dsn_file,omitempty"` // File holding the DSN, read again on every connection (alternative to dsn)
+}
+
+// ReadDSN returns the DSN to connect with. A DSN file is read again on every call, so
+// rotated credentials are picked up on reconnect.
+func (c *DatabaseConfig) ReadDSN() (string, error) {
+	if c.DSNFile == "" {
+		return c.DSN, nil
+	}
+	dsn, err := readSecretFile(c.DSNFile)
+	if err != nil {
+		return "", fmt.Errorf("dsn_file: %w", err)
+	}
+	return dsn, nil
 }
 
 // QueryParam represents a parameter for a query
 type QueryParam struct {
-	Name string `yaml:"name"`
-	Type string `yaml:"type"` // "int", "string", "float", etc.
+	Name      string `yaml:"name"`
+	Type      string `yaml:"type"`                // "int", "string", "float", etc. Append "[]" for arrays (e.g. "int[]")
+	Sensitive bool   `yaml:"sensitive,omitempty"` // Whether the value is redacted in logs
+}
+
+// IsArray reports whether the parameter holds a list of values (e.g. "int[]")
+func (p QueryParam) IsArray() bool {
+	return strings.HasSuffix(p.Type, "[]")
+}
+
+// ElementType returns the type of a single value, stripping the array suffix if present
+func (p QueryParam) ElementType() string {
+	return strings.TrimSuffix(p.Type, "[]")
 }
 
 // Query represents a single query configuration
 type Query struct {
-	SQL              string       `yaml:"sql"`
-	Params           []QueryParam `yaml:"params"`            // Parameters from request body
-	MiddlewareParams []QueryParam `yaml:"middleware_params"` // Parameters injected by middleware
+	Name               string        `yaml:"-"` // Name of the query, set when the configuration is loaded
+	SQL                string        `yaml:"sql"`
+	SQLFile            string        `yaml:"sql_file,omitempty"`             // File holding the SQL, relative to the YAML file (alternative to sql)
+	Params             []QueryParam  `yaml:"params"`                         // Parameters from request body
+	MiddlewareParams   []QueryParam  `yaml:"middleware_params"`              // Parameters injected by middleware
+	Methods            []string      `yaml:"methods,omitempty"`              // Allowed HTTP methods (GET and/or POST, default: POST)
+	Route              string        `yaml:"route,omitempty"`                // Optional custom route, e.g. "GET /users/{id}/orders"
+	NotifyChannels     []string      `yaml:"notify_channels,omitempty"`      // PostgreSQL NOTIFY channels that signal result changes
+	Cache              *QueryCache   `yaml:"cache,omitempty"`                // Optional in-memory result cache
+	Coalesce           bool          `yaml:"coalesce,omitempty"`             // Share one execution between identical concurrent calls
+	InvalidateOn       []string      `yaml:"invalidate_on,omitempty"`        // PostgreSQL NOTIFY channels that invalidate cached results
+	CacheControl       string        `yaml:"cache_control,omitempty"`        // Cache-Control header of successful responses, e.g. "public, max-age=60"
+	LastModifiedColumn string        `yaml:"last_modified_column,omitempty"` // Timestamp column whose latest value drives Last-Modified
+	Audit              bool          `yaml:"audit,omitempty"`                // Record executions in the audit log
+	SlowThreshold      time.Duration `yaml:"slow_threshold,omitempty"`       // Duration above which executions are logged as slow (overrides the global threshold)
+}
+
+// QueryCache configures the result cache of a query
+type QueryCache struct {
+	TTL        time.Duration `yaml:"ttl"`                   // How long a result is served from the cache
+	MaxEntries int           `yaml:"max_entries,omitempty"` // Maximum number of cached parameter combinations (default: 1000)
+}
+
+// AllowedMethods returns the HTTP methods the query can be executed with
+func (q Query) AllowedMethods() []string {
+	return allowedMethods(q.Methods)
+}
+
+// AllowsMethod reports whether the query can be executed with the given HTTP method
+func (q Query) AllowsMethod(method string) bool {
+	return allowsMethod(q.Methods, method)
+}
+
+// Composite represents a group of queries executed together in one consistent snapshot
+type Composite struct {
+	Queries map[string]string `yaml:"queries"`           // Response key -> name of the query to execute
+	Methods []string          `yaml:"methods,omitempty"` // Allowed HTTP methods (GET and/or POST, default: POST)
+}
+
+// AllowedMethods returns the HTTP methods the composite can be executed with
+func (c Composite) AllowedMethods() []string {
+	return allowedMethods(c.Methods)
+}
+
+// AllowsMethod reports whether the composite can be executed with the given HTTP method
+func (c Composite) AllowsMethod(method string) bool {
+	return allowsMethod(c.Methods, method)
+}
+
+// allowedMethods returns the configured HTTP methods, defaulting to POST
+func allowedMethods(methods []string) []string {
+	if len(methods) == 0 {
+		return []string{http.MethodPost}
+	}
+	return methods
+}
+
+// allowsMethod reports whether method is one of the configured HTTP methods. HEAD is
+// allowed wherever GET is.
+func allowsMethod(methods []string, method string) bool {
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+	for _, allowed := range allowedMethods(methods) {
+		if allowed == method {
+			return true
+		}
+	}
+	return false
 }
 
 // QueriesConfig represents the queries configuration
 type QueriesConfig struct {
-	Queries map[string]Query `yaml:"queries"`
+	Queries    map[string]Query     `yaml:"queries"`
+	Composites map[string]Composite `yaml:"composites,omitempty"`
+	Files      []string             `yaml:"-"` // YAML and SQL files the configuration was loaded from
+
+	positions map[string]string // "query" and "query.param" -> file:line:column of their definition
+}
+
+// CompositeParams returns the union of the body parameters of the composite's queries
+func (c *QueriesConfig) CompositeParams(composite Composite) []QueryParam {
+	var params []QueryParam
+	for _, queryName := range sortedKeys(composite.Queries) {
+		for _, param := range c.Queries[composite.Queries[queryName]].Params {
+			if _, exists := findParam(params, param.Name); !exists {
+				params = append(params, param)
+			}
+		}
+	}
+	return params
 }
 
 // MiddlewareConfig represents a single middleware configuration
@@ -37,9 +150,307 @@ type MiddlewareConfig struct {
 	Config map[string]interface{} `yaml:"config"` // Middleware-specific configuration
 }
 
+// BatchConfig configures the batch endpoint
+type BatchConfig struct {
+	MaxItems    int `yaml:"max_items,omitempty"`   // Maximum number of items per batch request (default: 50)
+	Concurrency int `yaml:"concurrency,omitempty"` // Maximum number of items executed in parallel (default: 4)
+}
+
+// GraphQLConfig configures the optional GraphQL endpoint
+type GraphQLConfig struct {
+	Enabled bool `yaml:"enabled"` // Whether to serve /graphql (default: false)
+}
+
+// GRPCConfig configures the optional gRPC listener
+type GRPCConfig struct {
+	Port string `yaml:"port"` // Port to serve gRPC on (disabled when empty)
+}
+
+// WebSocketConfig configures the optional WebSocket endpoint
+type WebSocketConfig struct {
+	Enabled          bool     `yaml:"enabled"`                     // Whether to serve /ws (default: false)
+	MaxConcurrency   int      `yaml:"max_concurrency,omitempty"`   // Maximum concurrent executions per connection (default: 4)
+	MaxSubscriptions int      `yaml:"max_subscriptions,omitempty"` // Maximum active subscriptions per connection (default: 16)
+	AllowedOrigins   []string `yaml:"allowed_origins,omitempty"`   // Cross-origin hosts allowed to connect (default: same origin only)
+}
+
+// JobsConfig configures the optional asynchronous export jobs
+type JobsConfig struct {
+	Enabled   bool          `yaml:"enabled"`              // Whether to serve /jobs (default: false)
+	Workers   int           `yaml:"workers,omitempty"`    // Number of jobs executed in parallel (default: 2)
+	MaxQueued int           `yaml:"max_queued,omitempty"` // Maximum number of jobs waiting for a worker (default: 100)
+	Directory string        `yaml:"directory,omitempty"`  // Directory results are spooled to (default: a directory in the system temp dir)
+	TTL       time.Duration `yaml:"ttl,omitempty"`        // How long finished jobs and their results are kept (default: 1h)
+}
+
+// CompressionConfig configures compression of HTTP responses
+type CompressionConfig struct {
+	Enabled    bool     `yaml:"enabled"`              // Whether to compress responses (default: false)
+	MinSize    int      `yaml:"min_size,omitempty"`   // Minimum response size in bytes to compress (default: 1024)
+	Algorithms []string `yaml:"algorithms,omitempty"` // Supported encodings in order of preference (default: zstd, br, gzip)
+}
+
+// MetricsConfig configures the optional Prometheus metrics endpoint
+type MetricsConfig struct {
+	Enabled bool `yaml:"enabled"` // Whether to serve /metrics (default: false)
+}
+
+// TracingConfig configures OpenTelemetry trace export over OTLP/HTTP
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled"`                // Whether to export traces (default: false)
+	Endpoint    string  `yaml:"endpoint,omitempty"`     // Collector host:port (default: OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4318)
+	Insecure    bool    `yaml:"insecure,omitempty"`     // Whether to export over plain HTTP instead of HTTPS
+	ServiceName string  `yaml:"service_name,omitempty"` // Service name reported with every span (default: simple-query-server)
+	SampleRatio float64 `yaml:"sample_ratio,omitempty"` // Fraction of new traces to sample (default: 1)
+}
+
+// LoggingConfig configures the structured log output
+type LoggingConfig struct {
+	Level         string `yaml:"level,omitempty"`          // Minimum level: debug, info, warn or error (default: info)
+	Format        string `yaml:"format,omitempty"`         // Output format: json or text (default: json)
+	LogParameters *bool  `yaml:"log_parameters,omitempty"` // Whether query parameter values are logged (default: true)
+}
+
+// AuditConfig configures the audit log of query executions
+type AuditConfig struct {
+	Enabled    bool              `yaml:"enabled"`               // Whether to record executions (default: false)
+	AllQueries bool              `yaml:"all_queries,omitempty"` // Record every query, not only queries with audit: true
+	Identity   []string          `yaml:"identity,omitempty"`    // Middleware parameters identifying the caller (default: all)
+	BufferSize int               `yaml:"buffer_size,omitempty"` // Entries waiting to be written before executions block (default: 1024)
+	Sinks      []AuditSinkConfig `yaml:"sinks"`                 // Destinations every entry is written to
+}
+
+// AuditSinkConfig configures a destination of the audit log
+type AuditSinkConfig struct {
+	Type       string `yaml:"type"`                  // file, stdout or postgres
+	Path       string `yaml:"path,omitempty"`        // file: path of the JSON-lines file
+	MaxSizeMB  int    `yaml:"max_size_mb,omitempty"` // file: size in megabytes at which the file is rotated (default: 100)
+	MaxBackups int    `yaml:"max_backups,omitempty"` // file: number of rotated files kept (default: 5)
+	DSN        string `yaml:"dsn,omitempty"`         // postgres: connection string (default: the database DSN)
+	DSNFile    string `yaml:"dsn_file,omitempty"`    // postgres: file holding the connection string (alternative to dsn)
+	Table      string `yaml:"table,omitempty"`       // postgres: table entries are inserted into, created if missing (default: audit_log)
+}
+
+// SlowQueryConfig configures the log of slow query executions
+type SlowQueryConfig struct {
+	Threshold  time.Duration `yaml:"threshold,omitempty"`   // Duration above which executions are logged as slow (disabled when zero)
+	Explain    bool          `yaml:"explain,omitempty"`     // Capture the EXPLAIN plan of slow executions
+	MaxEntries int           `yaml:"max_entries,omitempty"` // Number of recent slow executions kept (default: 100)
+}
+
+// AdminConfig configures the optional admin API listener
+type AdminConfig struct {
+	Address   string `yaml:"address"`              // Address to serve the admin API on, e.g. "127.0.0.1:9090" (disabled when empty)
+	Token     string `yaml:"token,omitempty"`      // Bearer token required on every admin request (optional)
+	TokenFile string `yaml:"token_file,omitempty"` // File holding the bearer token (alternative to token)
+}
+
 // ServerConfig represents the server configuration including middleware
 type ServerConfig struct {
-	Middleware []MiddlewareConfig `yaml:"middleware,omitempty"`
+	Middleware  []MiddlewareConfig `yaml:"middleware,omitempty"`
+	Batch       BatchConfig        `yaml:"batch,omitempty"`
+	GraphQL     GraphQLConfig      `yaml:"graphql,omitempty"`
+	GRPC        GRPCConfig         `yaml:"grpc,omitempty"`
+	WebSocket   WebSocketConfig    `yaml:"websocket,omitempty"`
+	Jobs        JobsConfig         `yaml:"jobs,omitempty"`
+	Compression CompressionConfig  `yaml:"compression,omitempty"`
+	Metrics     MetricsConfig      `yaml:"metrics,omitempty"`
+	Tracing     TracingConfig      `yaml:"tracing,omitempty"`
+	Logging     LoggingConfig      `yaml:"logging,omitempty"`
+	Audit       AuditConfig        `yaml:"audit,omitempty"`
+	SlowQueries SlowQueryConfig    `yaml:"slow_queries,omitempty"`
+	Admin       AdminConfig        `yaml:"admin,omitempty"`
+}
+
+// builtinPatterns are the endpoints served by the server itself, which custom routes must not shadow
+var builtinPatterns = []string{"/", "/health", "/queries", "/query/", "/batch", "/composite/", "/graphql", "/subscribe/", "/ws", "/jobs/", "/metrics"}
+
+// routeWildcardPattern matches {name} and {name...} wildcards in a route path
+var routeWildcardPattern = regexp.MustCompile(`\{(\w+)(\.\.\.)?\}`)
+
+// tablePattern matches table names, optionally schema-qualified, usable without quoting
+var tablePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*(\.[a-z_][a-z0-9_]*)?$`)
+
+// channelPattern matches NOTIFY channel names usable without quoting, as written in NOTIFY statements
+var channelPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// NotifyChannels returns the distinct NOTIFY channels declared by all queries for
+// subscriptions or cache invalidation, sorted
+func (c *QueriesConfig) NotifyChannels() []string {
+	seen := make(map[string]bool)
+	for _, query := range c.Queries {
+		for _, channel := range query.NotifyChannels {
+			seen[channel] = true
+		}
+		for _, channel := range query.InvalidateOn {
+			seen[channel] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// ParseRoute splits a custom route such as "GET /users/{id}/orders" into its method,
+// path and the names of the path parameters
+func ParseRoute(route string) (method string, path string, pathParams []string, err error) {
+	fields := strings.Fields(route)
+	if len(fields) != 2 {
+		return "", "", nil, fmt.Errorf("route must have the form 'METHOD /path'")
+	}
+
+	method, path = fields[0], fields[1]
+	if method != http.MethodGet && method != http.MethodPost {
+		return "", "", nil, fmt.Errorf("unsupported route method '%s' (supported: GET, POST)", method)
+	}
+	if !strings.HasPrefix(path, "/") {
+		return "", "", nil, fmt.Errorf("route path must start with '/'")
+	}
+
+	for _, match := range routeWildcardPattern.FindAllStringSubmatch(path, -1) {
+		pathParams = append(pathParams, match[1])
+	}
+
+	return method, path, pathParams, nil
+}
+
+// normalizeMethods upper-cases the configured HTTP methods in place and checks they are supported
+func normalizeMethods(methods []string) error {
+	for i, method := range methods {
+		normalized := strings.ToUpper(method)
+		if normalized != http.MethodGet && normalized != http.MethodPost {
+			return fmt.Errorf("has unsupported method '%s' (supported: GET, POST)", method)
+		}
+		methods[i] = normalized
+	}
+	return nil
+}
+
+// validateComposites checks that composites reference existing queries whose parameters agree on types
+func validateComposites(config *QueriesConfig) error {
+	for name, composite := range config.Composites {
+		if len(composite.Queries) == 0 {
+			return fmt.Errorf("composite %s must reference at least one query", name)
+		}
+
+		if err := normalizeMethods(composite.Methods); err != nil {
+			return fmt.Errorf("composite %s %w", name, err)
+		}
+
+		paramTypes := make(map[string]string)
+		for _, key := range sortedKeys(composite.Queries) {
+			queryName := composite.Queries[key]
+			query, exists := config.Queries[queryName]
+			if !exists {
+				return fmt.Errorf("composite %s references unknown query '%s' under key '%s'", name, queryName, key)
+			}
+
+			// The request parameters are shared between the queries, so their types must agree
+			for _, param := range query.Params {
+				if existing, seen := paramTypes[param.Name]; seen && existing != param.Type {
+					return fmt.Errorf("composite %s has conflicting types for parameter '%s' (%s and %s)", name, param.Name, existing, param.Type)
+				}
+				paramTypes[param.Name] = param.Type
+			}
+		}
+	}
+
+	return nil
+}
+
+// sortedKeys returns the keys of a string map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateRoutes checks custom routes for syntax errors, undeclared path parameters
+// and conflicts with each other or with the built-in endpoints
+func validateRoutes(queries map[string]Query) error {
+	names := make([]string, 0, len(queries))
+	for name := range queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Register every route on a scratch mux, which panics on conflicting patterns
+	mux := http.NewServeMux()
+	for _, pattern := range builtinPatterns {
+		mux.HandleFunc(pattern, func(http.ResponseWriter, *http.Request) {})
+	}
+	routesByPattern := make(map[string]string)
+
+	for _, name := range names {
+		query := queries[name]
+		if query.Route == "" {
+			continue
+		}
+
+		_, path, pathParams, err := ParseRoute(query.Route)
+		if err != nil {
+			return fmt.Errorf("query %s has invalid route '%s': %w", name, query.Route, err)
+		}
+
+		if builtin := shadowedBuiltin(path); builtin != "" {
+			return fmt.Errorf("query %s route '%s' conflicts with built-in endpoint %s", name, query.Route, builtin)
+		}
+
+		for _, pathParam := range pathParams {
+			param, declared := findParam(query.Params, pathParam)
+			if !declared {
+				return fmt.Errorf("query %s route '%s' uses path parameter '%s' which is not declared in params", name, query.Route, pathParam)
+			}
+			if param.IsArray() {
+				return fmt.Errorf("query %s route '%s' binds path parameter '%s' to an array type", name, query.Route, pathParam)
+			}
+		}
+
+		if other, exists := routesByPattern[query.Route]; exists {
+			return fmt.Errorf("query %s route '%s' is already used by query %s", name, query.Route, other)
+		}
+		if err := registerRoute(mux, query.Route); err != nil {
+			return fmt.Errorf("query %s route '%s' conflicts with another route: %w", name, query.Route, err)
+		}
+		routesByPattern[query.Route] = name
+	}
+
+	return nil
+}
+
+// shadowedBuiltin returns the built-in endpoint that a route path would take over, if any
+func shadowedBuiltin(path string) string {
+	for _, pattern := range builtinPatterns {
+		if path == pattern || path == pattern+"{$}" {
+			return pattern
+		}
+		if pattern != "/" && strings.HasSuffix(pattern, "/") && strings.HasPrefix(path, pattern) {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// registerRoute registers a route on the given mux, converting registration panics into errors
+func registerRoute(mux *http.ServeMux, route string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	mux.HandleFunc(route, func(http.ResponseWriter, *http.Request) {})
+	return nil
+}
+
+// findParam looks up a parameter definition by name
+func findParam(params []QueryParam, name string) (QueryParam, bool) {
+	for _, param := range params {
+		if param.Name == name {
+			return param, true
+		}
+	}
+	return QueryParam{}, false
 }
 
 // LoadDatabaseConfig loads database configuration from a YAML file
@@ -50,31 +461,69 @@ func LoadDatabaseConfig(path string) (*DatabaseConfig, error) {
 	}
 
 	var config DatabaseConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	if _, err := decodeYAML(path, data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse database config YAML: %w", err)
 	}
 
+	// The DSN file is read here to validate it; connections read it again
+	if config.DSNFile != "" {
+		if config.DSN != "" {
+			return nil, fmt.Errorf("dsn and dsn_file must not both be set")
+		}
+		config.DSN, err = config.ReadDSN()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Basic validation
 	if config.Type == "" {
 		return nil, fmt.Errorf("database type is required")
 	}
 	if config.DSN == "" {
-		return nil, fmt.Errorf("database DSN is required")
+		return nil, fmt.Errorf("database DSN is required (dsn or dsn_file)")
 	}
 
 	return &config, nil
 }
 
-// LoadQueriesConfig loads queries configuration from a YAML file
+// LoadQueriesConfig loads queries configuration from a YAML file, from all YAML files of a
+// directory and its subdirectories, or from the YAML files matching a glob pattern. The files
+// are merged; a query or composite may only be defined in one of them.
 func LoadQueriesConfig(path string) (*QueriesConfig, error) {
-	data, err := os.ReadFile(path)
+	files, err := QueriesConfigFiles(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read queries config file %s: %w", path, err)
+		return nil, err
 	}
 
-	var config QueriesConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse queries config YAML: %w", err)
+	config := QueriesConfig{Queries: make(map[string]Query), positions: make(map[string]string)}
+	compositeFiles := make(map[string]string)
+	for _, file := range files {
+		fileConfig, err := loadQueriesFile(file)
+		if err != nil {
+			return nil, err
+		}
+		config.Files = append(config.Files, fileConfig.Files...)
+
+		for name, query := range fileConfig.Queries {
+			if existing, duplicate := config.positions[name]; duplicate {
+				return nil, fmt.Errorf("query %s is defined in both %s and %s", name, existing, fileConfig.positions[name])
+			}
+			config.Queries[name] = query
+		}
+		for key, position := range fileConfig.positions {
+			config.positions[key] = position
+		}
+		for name, composite := range fileConfig.Composites {
+			if existing, duplicate := compositeFiles[name]; duplicate {
+				return nil, fmt.Errorf("composite %s is defined in both %s and %s", name, existing, file)
+			}
+			compositeFiles[name] = file
+			if config.Composites == nil {
+				config.Composites = make(map[string]Composite)
+			}
+			config.Composites[name] = composite
+		}
 	}
 
 	// Basic validation
@@ -83,14 +532,170 @@ func LoadQueriesConfig(path string) (*QueriesConfig, error) {
 	}
 
 	for name, query := range config.Queries {
-		if query.SQL == "" {
-			return nil, fmt.Errorf("query %s must have SQL defined", name)
+		if err := validateQuery(name, query); err != nil {
+			return nil, fmt.Errorf("%s%w", config.positionPrefix(name), err)
 		}
+
+		query.Name = name
+		config.Queries[name] = query
+	}
+
+	if err := validateRoutes(config.Queries); err != nil {
+		return nil, err
+	}
+
+	if err := validateComposites(&config); err != nil {
+		return nil, err
 	}
 
 	return &config, nil
 }
 
+// validateQuery checks the settings of a single query
+func validateQuery(name string, query Query) error {
+	if query.SQL == "" {
+		return fmt.Errorf("query %s must have SQL defined (sql or sql_file)", name)
+	}
+
+	if err := normalizeMethods(query.Methods); err != nil {
+		return fmt.Errorf("query %s %w", name, err)
+	}
+
+	for _, channel := range query.NotifyChannels {
+		if !channelPattern.MatchString(channel) {
+			return fmt.Errorf("query %s has invalid notify channel '%s'", name, channel)
+		}
+	}
+
+	if query.Cache != nil && (query.Cache.TTL <= 0 || query.Cache.MaxEntries < 0) {
+		return fmt.Errorf("query %s cache must have a positive ttl and non-negative max_entries", name)
+	}
+
+	if query.SlowThreshold < 0 {
+		return fmt.Errorf("query %s slow_threshold must not be negative", name)
+	}
+
+	if len(query.InvalidateOn) > 0 && query.Cache == nil {
+		return fmt.Errorf("query %s declares invalidate_on without a cache", name)
+	}
+	for _, channel := range query.InvalidateOn {
+		if !channelPattern.MatchString(channel) {
+			return fmt.Errorf("query %s has invalid invalidate_on channel '%s'", name, channel)
+		}
+	}
+	return nil
+}
+
+// QueriesConfigFiles returns the YAML files a queries configuration path refers to, sorted:
+// the file itself, the .yaml and .yml files of a directory and its subdirectories, or the
+// files matching a glob pattern. Hidden files and directories are skipped, such as the
+// "..<timestamp>" directories a mounted Kubernetes ConfigMap links its files into.
+func QueriesConfigFiles(path string) ([]string, error) {
+	if strings.ContainsAny(path, "*?[") {
+		files, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid queries config pattern %s: %w", path, err)
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no queries config files match %s", path)
+		}
+		sort.Strings(files)
+		return files, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		// Read errors are reported when loading the file
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if file != path && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if ext := filepath.Ext(file); !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queries config directory %s: %w", path, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no queries config files in directory %s", path)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// loadQueriesFile parses one queries YAML file and reads the SQL files its queries refer to,
+// relative to the YAML file
+func loadQueriesFile(path string) (*QueriesConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queries config file %s: %w", path, err)
+	}
+
+	var config QueriesConfig
+	document, err := decodeYAML(path, data, &config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse queries config YAML: %w", err)
+	}
+	config.Files = []string{path}
+	config.positions = make(map[string]string)
+
+	for _, name := range sortedKeys(config.Queries) {
+		query := config.Queries[name]
+		queryNode := lookupNode(document, "queries", name)
+		config.positions[name] = position(path, queryNode)
+		for _, param := range query.MiddlewareParams {
+			config.positions[name+"."+param.Name] = position(path, paramNode(queryNode, "middleware_params", param.Name))
+		}
+
+		if query.SQLFile != "" {
+			sqlPath, err := readSQLFile(path, name, &query)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", config.positions[name], err)
+			}
+			config.Queries[name] = query
+			config.Files = append(config.Files, sqlPath)
+		}
+
+		if err := checkQueryParams(path, name, query, queryNode); err != nil {
+			return nil, err
+		}
+	}
+
+	return &config, nil
+}
+
+// readSQLFile sets the SQL of a query from its sql_file, relative to the YAML file at path,
+// and returns the path of the SQL file
+func readSQLFile(path string, name string, query *Query) (string, error) {
+	if query.SQL != "" {
+		return "", fmt.Errorf("query %s must define either sql or sql_file, not both", name)
+	}
+
+	sqlPath := query.SQLFile
+	if !filepath.IsAbs(sqlPath) {
+		sqlPath = filepath.Join(filepath.Dir(path), sqlPath)
+	}
+	sql, err := os.ReadFile(sqlPath)
+	if err != nil {
+		return "", fmt.Errorf("query %s: failed to read sql_file: %w", name, err)
+	}
+	// Editors commonly terminate statements, which would break statements built around the SQL
+	query.SQL = strings.TrimSuffix(strings.TrimSpace(string(sql)), ";")
+	return sqlPath, nil
+}
+
 // LoadServerConfig loads server configuration from a YAML file
 func LoadServerConfig(path string) (*ServerConfig, error) {
 	data, err := os.ReadFile(path)
@@ -99,9 +704,107 @@ func LoadServerConfig(path string) (*ServerConfig, error) {
 	}
 
 	var config ServerConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	if _, err := decodeYAML(path, data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse server config YAML: %w", err)
 	}
+	if err := resolveServerSecrets(&config); err != nil {
+		return nil, err
+	}
+
+	if config.Batch.MaxItems < 0 {
+		return nil, fmt.Errorf("batch max_items must not be negative")
+	}
+	if config.Batch.Concurrency < 0 {
+		return nil, fmt.Errorf("batch concurrency must not be negative")
+	}
+	if config.WebSocket.MaxConcurrency < 0 {
+		return nil, fmt.Errorf("websocket max_concurrency must not be negative")
+	}
+	if config.WebSocket.MaxSubscriptions < 0 {
+		return nil, fmt.Errorf("websocket max_subscriptions must not be negative")
+	}
+	if config.Jobs.Workers < 0 || config.Jobs.MaxQueued < 0 || config.Jobs.TTL < 0 {
+		return nil, fmt.Errorf("jobs workers, max_queued and ttl must not be negative")
+	}
+	switch config.Logging.Level {
+	case "", "debug", "info", "warn", "error":
+	default:
+		return nil, fmt.Errorf("logging level '%s' is not supported (supported: debug, info, warn, error)", config.Logging.Level)
+	}
+	if config.Logging.Format != "" && config.Logging.Format != "json" && config.Logging.Format != "text" {
+		return nil, fmt.Errorf("logging format '%s' is not supported (supported: json, text)", config.Logging.Format)
+	}
+	if config.Tracing.SampleRatio < 0 || config.Tracing.SampleRatio > 1 {
+		return nil, fmt.Errorf("tracing sample_ratio must be between 0 and 1")
+	}
+	if config.Compression.MinSize < 0 {
+		return nil, fmt.Errorf("compression min_size must not be negative")
+	}
+	for _, algorithm := range config.Compression.Algorithms {
+		if algorithm != "gzip" && algorithm != "zstd" && algorithm != "br" {
+			return nil, fmt.Errorf("compression algorithm '%s' is not supported (supported: gzip, zstd, br)", algorithm)
+		}
+	}
+	if config.SlowQueries.Threshold < 0 || config.SlowQueries.MaxEntries < 0 {
+		return nil, fmt.Errorf("slow_queries threshold and max_entries must not be negative")
+	}
+	if err := validateAudit(config.Audit); err != nil {
+		return nil, err
+	}
+	if config.Admin.Token != "" && config.Admin.Address == "" {
+		return nil, fmt.Errorf("admin token requires an admin address")
+	}
 
 	return &config, nil
 }
+
+// resolveServerSecrets reads the secrets of the server config given as files
+func resolveServerSecrets(config *ServerConfig) error {
+	for i, middlewareConfig := range config.Middleware {
+		if err := resolveSecretKeys(middlewareConfig.Config); err != nil {
+			return fmt.Errorf("middleware %d (%s): %w", i, middlewareConfig.Type, err)
+		}
+	}
+	for i := range config.Audit.Sinks {
+		sink := &config.Audit.Sinks[i]
+		if err := resolveSecret("dsn", &sink.DSN, sink.DSNFile); err != nil {
+			return fmt.Errorf("audit sink %d: %w", i, err)
+		}
+	}
+	if err := resolveSecret("token", &config.Admin.Token, config.Admin.TokenFile); err != nil {
+		return fmt.Errorf("admin: %w", err)
+	}
+	return nil
+}
+
+// validateAudit checks that an enabled audit log has usable sinks
+func validateAudit(audit AuditConfig) error {
+	if !audit.Enabled {
+		return nil
+	}
+	if len(audit.Sinks) == 0 {
+		return fmt.Errorf("audit requires at least one sink")
+	}
+	if audit.BufferSize < 0 {
+		return fmt.Errorf("audit buffer_size must not be negative")
+	}
+	for i, sink := range audit.Sinks {
+		switch sink.Type {
+		case "file":
+			if sink.Path == "" {
+				return fmt.Errorf("audit sink %d of type file requires a path", i)
+			}
+			if sink.MaxSizeMB < 0 || sink.MaxBackups < 0 {
+				return fmt.Errorf("audit sink %d max_size_mb and max_backups must not be negative", i)
+			}
+		case "stdout":
+		case "postgres":
+			if sink.Table != "" && !tablePattern.MatchString(sink.Table) {
+				return fmt.Errorf("audit sink %d has invalid table name '%s'", i, sink.Table)
+			}
+		default:
+			return fmt.Errorf("audit sink type '%s' is not supported (supported: file, stdout, postgres)", sink.Type)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,525 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTempFile writes content to a file in a temporary directory and returns its path
+func writeTempFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	return path
+}
+
+func TestLoadQueriesConfig_Routes(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		errorMsg string
+	}{
+		{
+			name: "valid route",
+			yaml: `
+queries:
+  get_user_orders:
+    sql: "SELECT * FROM orders WHERE user_id = :id"
+    route: "GET /users/{id}/orders"
+    params:
+      - name: id
+        type: int
+`,
+		},
+		{
+			name: "missing method",
+			yaml: `
+queries:
+  get_user_orders:
+    sql: "SELECT * FROM orders WHERE user_id = :id"
+    route: "/users/{id}/orders"
+    params:
+      - name: id
+        type: int
+`,
+			errorMsg: "route must have the form 'METHOD /path'",
+		},
+		{
+			name: "unsupported method",
+			yaml: `
+queries:
+  delete_user:
+    sql: "SELECT 1"
+    route: "DELETE /users"
+`,
+			errorMsg: "unsupported route method 'DELETE'",
+		},
+		{
+			name: "undeclared path parameter",
+			yaml: `
+queries:
+  get_user_orders:
+    sql: "SELECT * FROM orders WHERE user_id = :id"
+    route: "GET /users/{user_id}/orders"
+    params:
+      - name: id
+        type: int
+`,
+			errorMsg: "path parameter 'user_id' which is not declared",
+		},
+		{
+			name: "duplicate route",
+			yaml: `
+queries:
+  a:
+    sql: "SELECT 1"
+    route: "GET /things"
+  b:
+    sql: "SELECT 2"
+    route: "GET /things"
+`,
+			errorMsg: "query b route 'GET /things' is already used by query a",
+		},
+		{
+			name: "ambiguous routes",
+			yaml: `
+queries:
+  a:
+    sql: "SELECT :id"
+    route: "GET /users/{id}"
+    params:
+      - name: id
+        type: int
+  b:
+    sql: "SELECT :name"
+    route: "GET /users/{name}"
+    params:
+      - name: name
+        type: string
+`,
+			errorMsg: "conflicts with another route",
+		},
+		{
+			name: "shadows built-in endpoint",
+			yaml: `
+queries:
+  a:
+    sql: "SELECT 1"
+    route: "GET /health"
+`,
+			errorMsg: "conflicts with built-in endpoint /health",
+		},
+		{
+			name: "shadows built-in prefix",
+			yaml: `
+queries:
+  a:
+    sql: "SELECT 1"
+    route: "GET /query/a"
+`,
+			errorMsg: "conflicts with built-in endpoint /query/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTempFile(t, "queries.yaml", tt.yaml)
+			_, err := LoadQueriesConfig(path)
+
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error containing %q but got none", tt.errorMsg)
+			}
+			if !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %q", tt.errorMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestLoadQueriesConfig_Composites(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		errorMsg string
+	}{
+		{
+			name: "valid composite",
+			yaml: `
+queries:
+  order_totals:
+    sql: "SELECT SUM(total) FROM orders WHERE user_id = :user_id"
+    params:
+      - name: user_id
+        type: int
+  order_lines:
+    sql: "SELECT * FROM orders WHERE user_id = :user_id"
+    params:
+      - name: user_id
+        type: int
+composites:
+  order_summary:
+    methods: [get]
+    queries:
+      totals: order_totals
+      lines: order_lines
+`,
+		},
+		{
+			name: "unknown query",
+			yaml: `
+queries:
+  a:
+    sql: "SELECT 1"
+composites:
+  summary:
+    queries:
+      first: a
+      second: b
+`,
+			errorMsg: "composite summary references unknown query 'b' under key 'second'",
+		},
+		{
+			name: "empty composite",
+			yaml: `
+queries:
+  a:
+    sql: "SELECT 1"
+composites:
+  summary:
+    queries: {}
+`,
+			errorMsg: "composite summary must reference at least one query",
+		},
+		{
+			name: "conflicting parameter types",
+			yaml: `
+queries:
+  a:
+    sql: "SELECT :id"
+    params:
+      - name: id
+        type: int
+  b:
+    sql: "SELECT :id"
+    params:
+      - name: id
+        type: string
+composites:
+  summary:
+    queries:
+      first: a
+      second: b
+`,
+			errorMsg: "conflicting types for parameter 'id'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTempFile(t, "queries.yaml", tt.yaml)
+			config, err := LoadQueriesConfig(path)
+
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				for name, composite := range config.Composites {
+					if len(config.CompositeParams(composite)) != 1 {
+						t.Errorf("composite %s: expected shared parameters to be merged, got %+v", name, config.CompositeParams(composite))
+					}
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error containing %q but got none", tt.errorMsg)
+			}
+			if !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %q", tt.errorMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestLoadQueriesConfig_NotifyChannels(t *testing.T) {
+	tests := []struct {
+		name             string
+		yaml             string
+		expectedChannels []string
+		errorMsg         string
+	}{
+		{
+			name: "channels are collected across queries",
+			yaml: `
+queries:
+  open_orders:
+    sql: "SELECT * FROM orders WHERE status = 'open'"
+    notify_channels: [orders_changed]
+  order_stats:
+    sql: "SELECT count(*) FROM orders"
+    notify_channels: [orders_changed, stats_changed]
+  users:
+    sql: "SELECT * FROM users"
+`,
+			expectedChannels: []string{"orders_changed", "stats_changed"},
+		},
+		{
+			name: "invalidation channels are included",
+			yaml: `
+queries:
+  products:
+    sql: "SELECT * FROM products"
+    cache:
+      ttl: 1h
+    invalidate_on: [products_changed]
+`,
+			expectedChannels: []string{"products_changed"},
+		},
+		{
+			name: "invalidate_on requires a cache",
+			yaml: `
+queries:
+  products:
+    sql: "SELECT * FROM products"
+    invalidate_on: [products_changed]
+`,
+			errorMsg: "query products declares invalidate_on without a cache",
+		},
+		{
+			name: "invalid channel name",
+			yaml: `
+queries:
+  open_orders:
+    sql: "SELECT * FROM orders"
+    notify_channels: ["Orders-Changed"]
+`,
+			errorMsg: "query open_orders has invalid notify channel 'Orders-Changed'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTempFile(t, "queries.yaml", tt.yaml)
+			config, err := LoadQueriesConfig(path)
+
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !reflect.DeepEqual(config.NotifyChannels(), tt.expectedChannels) {
+					t.Errorf("expected channels %v, got %v", tt.expectedChannels, config.NotifyChannels())
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error containing %q but got none", tt.errorMsg)
+			}
+			if !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %q", tt.errorMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestLoadQueriesConfig_Cache(t *testing.T) {
+	path := writeTempFile(t, "queries.yaml", `
+queries:
+  top_products:
+    sql: "SELECT * FROM products ORDER BY sales DESC LIMIT 10"
+    cache:
+      ttl: 30s
+      max_entries: 100
+`)
+	config, err := LoadQueriesConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	query := config.Queries["top_products"]
+	if query.Name != "top_products" {
+		t.Errorf("expected query name to be set, got %q", query.Name)
+	}
+	if query.Cache == nil || query.Cache.TTL != 30*time.Second || query.Cache.MaxEntries != 100 {
+		t.Errorf("unexpected cache config: %+v", query.Cache)
+	}
+
+	path = writeTempFile(t, "queries.yaml", `
+queries:
+  top_products:
+    sql: "SELECT 1"
+    cache:
+      max_entries: 100
+`)
+	if _, err := LoadQueriesConfig(path); err == nil || !strings.Contains(err.Error(), "cache must have a positive ttl") {
+		t.Errorf("expected cache validation error, got %v", err)
+	}
+}
+
+// writeFiles writes files relative to a temporary directory and returns the directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestLoadQueriesConfig_MultipleFiles(t *testing.T) {
+	files := map[string]string{
+		"users.yaml": `
+queries:
+  get_user:
+    sql: "SELECT * FROM users WHERE id = :id"
+    params:
+      - name: id
+        type: int
+`,
+		"reports/reports.yml": `
+queries:
+  monthly_report:
+    sql_file: sql/monthly.sql
+composites:
+  dashboard:
+    queries:
+      report: monthly_report
+      user: get_user
+`,
+		"reports/sql/monthly.sql": "SELECT date_trunc('month', created_at) AS month, COUNT(*)\nFROM orders\nGROUP BY 1;\n",
+		"README.md":               "not a config file",
+	}
+
+	t.Run("Directory", func(t *testing.T) {
+		dir := writeFiles(t, files)
+		config, err := LoadQueriesConfig(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(config.Queries) != 2 || len(config.Composites) != 1 {
+			t.Fatalf("expected queries and composites of all files, got %+v", config)
+		}
+		report := config.Queries["monthly_report"]
+		if report.SQL != "SELECT date_trunc('month', created_at) AS month, COUNT(*)\nFROM orders\nGROUP BY 1" {
+			t.Errorf("expected SQL read from the file without the terminator, got %q", report.SQL)
+		}
+		expectedFiles := []string{
+			filepath.Join(dir, "reports/reports.yml"),
+			filepath.Join(dir, "reports/sql/monthly.sql"),
+			filepath.Join(dir, "users.yaml"),
+		}
+		if !reflect.DeepEqual(config.Files, expectedFiles) {
+			t.Errorf("expected files %v, got %v", expectedFiles, config.Files)
+		}
+	})
+
+	t.Run("ConfigMapDirectory", func(t *testing.T) {
+		// A mounted ConfigMap links its files through ..data into a timestamped directory
+		dir := writeFiles(t, map[string]string{
+			"..2024_01_01_00_00_00.000000001/users.yaml":   files["users.yaml"],
+			"..2024_01_01_00_00_00.000000001/reports.yml":  "queries:\n  monthly_report:\n    sql: SELECT 1\n",
+			"..2024_01_01_00_00_00.000000001/.hidden.yaml": "not: [valid",
+		})
+		if err := os.Symlink("..2024_01_01_00_00_00.000000001", filepath.Join(dir, "..data")); err != nil {
+			t.Fatalf("failed to create symlink: %v", err)
+		}
+		for _, name := range []string{"users.yaml", "reports.yml"} {
+			if err := os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)); err != nil {
+				t.Fatalf("failed to create symlink: %v", err)
+			}
+		}
+
+		config, err := LoadQueriesConfig(dir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(config.Queries) != 2 {
+			t.Errorf("expected the queries of both files, got %v", config.Queries)
+		}
+		expectedFiles := []string{filepath.Join(dir, "reports.yml"), filepath.Join(dir, "users.yaml")}
+		if !reflect.DeepEqual(config.Files, expectedFiles) {
+			t.Errorf("expected files %v, got %v", expectedFiles, config.Files)
+		}
+	})
+
+	t.Run("Glob", func(t *testing.T) {
+		dir := writeFiles(t, files)
+		config, err := LoadQueriesConfig(filepath.Join(dir, "*.yaml"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, exists := config.Queries["monthly_report"]; exists || len(config.Queries) != 1 {
+			t.Errorf("expected only the queries of matching files, got %v", config.Queries)
+		}
+
+		if _, err := LoadQueriesConfig(filepath.Join(dir, "*.json")); err == nil || !strings.Contains(err.Error(), "no queries config files match") {
+			t.Errorf("expected error for a pattern without matches, got %v", err)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		errorMsg string
+	}{
+		{
+			name: "duplicate query",
+			files: map[string]string{
+				"a.yaml": "queries:\n  get_user:\n    sql: SELECT 1\n",
+				"b.yaml": "queries:\n  get_user:\n    sql: SELECT 2\n",
+			},
+			errorMsg: "query get_user is defined in both",
+		},
+		{
+			name: "duplicate composite",
+			files: map[string]string{
+				"a.yaml": "queries:\n  one:\n    sql: SELECT 1\ncomposites:\n  all:\n    queries: {one: one}\n",
+				"b.yaml": "composites:\n  all:\n    queries: {one: one}\n",
+			},
+			errorMsg: "composite all is defined in both",
+		},
+		{
+			name:     "sql and sql_file",
+			files:    map[string]string{"a.yaml": "queries:\n  one:\n    sql: SELECT 1\n    sql_file: one.sql\n", "one.sql": "SELECT 1"},
+			errorMsg: "either sql or sql_file, not both",
+		},
+		{
+			name:     "missing sql_file",
+			files:    map[string]string{"a.yaml": "queries:\n  one:\n    sql_file: one.sql\n"},
+			errorMsg: "failed to read sql_file",
+		},
+		{
+			name:     "empty directory",
+			files:    map[string]string{"notes.txt": "no configs here"},
+			errorMsg: "no queries config files in directory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadQueriesConfig(writeFiles(t, tt.files))
+			if err == nil {
+				t.Fatalf("expected error containing %q but got none", tt.errorMsg)
+			}
+			if !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %q", tt.errorMsg, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sqlParamPattern matches :param references in query SQL, as the executor converts them
+var sqlParamPattern = regexp.MustCompile(`:(\w+)`)
+
+// position returns the file, line and column of a node, e.g. "queries.yaml:12:5"
+func position(path string, node *yaml.Node) string {
+	return fmt.Sprintf("%s:%d:%d", path, node.Line, node.Column)
+}
+
+// checkKnownFields reports the first mapping key below a node that the type it is decoded
+// into has no field for. Nodes that do not match the type are left to the decoder to report.
+func checkKnownFields(path string, node *yaml.Node, t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			if err := checkKnownFields(path, child, t); err != nil {
+				return err
+			}
+		}
+	case yaml.AliasNode:
+		return checkKnownFields(path, node.Alias, t)
+	case yaml.SequenceNode:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return nil
+		}
+		for _, child := range node.Content {
+			if err := checkKnownFields(path, child, t.Elem()); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		switch t.Kind() {
+		case reflect.Map:
+			for i := 1; i < len(node.Content); i += 2 {
+				if err := checkKnownFields(path, node.Content[i], t.Elem()); err != nil {
+					return err
+				}
+			}
+		case reflect.Struct:
+			fields := yamlFields(t)
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				if key.Tag == "!!merge" {
+					if err := checkKnownFields(path, value, t); err != nil {
+						return err
+					}
+					continue
+				}
+				field, known := fields[key.Value]
+				if !known {
+					return fmt.Errorf("%s: unknown field '%s'", position(path, key), key.Value)
+				}
+				if err := checkKnownFields(path, value, field); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// yamlFields returns the types of the fields of a struct by their YAML key
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// lookupNode returns the node at a path of mapping keys below a document, or nil
+func lookupNode(node *yaml.Node, keys ...string) *yaml.Node {
+	if node != nil && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	for _, key := range keys {
+		if node == nil || node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var value *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				value = node.Content[i+1]
+			}
+		}
+		node = value
+	}
+	return node
+}
+
+// paramNode returns the name node of a declared parameter below a query node, or the query
+// node itself
+func paramNode(queryNode *yaml.Node, list string, name string) *yaml.Node {
+	if params := lookupNode(queryNode, list); params != nil && params.Kind == yaml.SequenceNode {
+		for _, param := range params.Content {
+			if nameNode := lookupNode(param, "name"); nameNode != nil && nameNode.Value == name {
+				return nameNode
+			}
+		}
+	}
+	return queryNode
+}
+
+// SQLParams returns the names of the :param references in the SQL of a query, in order of
+// first appearance
+func (q Query) SQLParams() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range sqlParamPattern.FindAllStringSubmatch(q.SQL, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// checkQueryParams checks that the SQL of a query references exactly its declared
+// parameters. Errors are located with the query's node in the file it was loaded from.
+func checkQueryParams(path string, name string, query Query, queryNode *yaml.Node) error {
+	referenced := make(map[string]bool)
+	for _, param := range query.SQLParams() {
+		referenced[param] = true
+		if _, declared := findParam(query.Params, param); declared {
+			continue
+		}
+		if _, declared := findParam(query.MiddlewareParams, param); declared {
+			continue
+		}
+		sqlNode := lookupNode(queryNode, "sql")
+		if sqlNode == nil {
+			sqlNode = lookupNode(queryNode, "sql_file")
+		}
+		if sqlNode == nil {
+			sqlNode = queryNode
+		}
+		return fmt.Errorf("%s: query %s references parameter '%s' that is not declared in params or middleware_params", position(path, sqlNode), name, param)
+	}
+
+	for _, list := range []struct {
+		key    string
+		params []QueryParam
+	}{{"params", query.Params}, {"middleware_params", query.MiddlewareParams}} {
+		for _, param := range list.params {
+			if !referenced[param.Name] {
+				return fmt.Errorf("%s: query %s declares %s '%s' that is not used in its SQL", position(path, paramNode(queryNode, list.key, param.Name)), name, list.key, param.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckMiddlewareParams checks that every middleware parameter declared by a query is one
+// of the parameters produced by the configured middleware
+func (c *QueriesConfig) CheckMiddlewareParams(produced []string) error {
+	producedSet := make(map[string]bool, len(produced))
+	for _, name := range produced {
+		producedSet[name] = true
+	}
+
+	for _, name := range sortedKeys(c.Queries) {
+		for _, param := range c.Queries[name].MiddlewareParams {
+			if producedSet[param.Name] {
+				continue
+			}
+			available := "no middleware produces parameters"
+			if len(produced) > 0 {
+				sorted := append([]string(nil), produced...)
+				sort.Strings(sorted)
+				available = "produced: " + strings.Join(sorted, ", ")
+			}
+			return fmt.Errorf("%squery %s declares middleware_params '%s' that no configured middleware produces (%s)", c.positionPrefix(name+"."+param.Name), name, param.Name, available)
+		}
+	}
+	return nil
+}
+
+// positionPrefix returns the recorded position of a query or parameter as an error message
+// prefix, or nothing for configurations not loaded from files
+func (c *QueriesConfig) positionPrefix(key string) string {
+	if c.positions[key] == "" {
+		return ""
+	}
+	return c.positions[key] + ": "
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadQueriesConfig_Strict(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		errorMsg string
+	}{
+		{
+			name: "unknown query field",
+			content: `queries:
+  get_user:
+    sql: "SELECT * FROM users WHERE id = :user_id"
+    middleware_param:
+      - name: user_id
+        type: string
+`,
+			errorMsg: "queries.yaml:4:5: unknown field 'middleware_param'",
+		},
+		{
+			name: "unknown param field",
+			content: `queries:
+  get_user:
+    sql: "SELECT * FROM users WHERE id = :id"
+    params:
+      - name: id
+        typ: int
+`,
+			errorMsg: "queries.yaml:6:9: unknown field 'typ'",
+		},
+		{
+			name: "undeclared parameter",
+			content: `queries:
+  get_user:
+    sql: "SELECT * FROM users WHERE id = :id AND tenant_id = :tenant_id"
+    params:
+      - name: id
+        type: int
+`,
+			errorMsg: "queries.yaml:3:10: query get_user references parameter 'tenant_id' that is not declared",
+		},
+		{
+			name: "unused parameter",
+			content: `queries:
+  get_user:
+    sql: "SELECT * FROM users WHERE id = :id"
+    params:
+      - name: id
+        type: int
+      - name: limit
+        type: int
+`,
+			errorMsg: "queries.yaml:7:15: query get_user declares params 'limit' that is not used in its SQL",
+		},
+		{
+			name: "unused middleware parameter",
+			content: `queries:
+  get_user:
+    sql: "SELECT * FROM users"
+    middleware_params:
+      - name: user_id
+        type: string
+`,
+			errorMsg: "queries.yaml:5:15: query get_user declares middleware_params 'user_id' that is not used in its SQL",
+		},
+		{
+			name: "invalid setting",
+			content: `queries:
+  get_user:
+    sql: "SELECT 1"
+    slow_threshold: -1s
+`,
+			errorMsg: "queries.yaml:3:5: query get_user slow_threshold must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTempFile(t, "queries.yaml", tt.content)
+			_, err := LoadQueriesConfig(path)
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestLoadServerConfig_UnknownField(t *testing.T) {
+	path := writeTempFile(t, "server.yaml", "admin:\n  address: localhost:9090\n  tokn: s3cret\n")
+	_, err := LoadServerConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "server.yaml:3:3: unknown field 'tokn'") {
+		t.Errorf("expected error with the position of the unknown field, got %v", err)
+	}
+}
+
+func TestCheckMiddlewareParams(t *testing.T) {
+	path := writeTempFile(t, "queries.yaml", `queries:
+  get_profile:
+    sql: "SELECT * FROM users WHERE id = :user_id AND tenant_id = :tenant_id"
+    middleware_params:
+      - name: user_id
+        type: string
+      - name: tenant_id
+        type: string
+`)
+	config, err := LoadQueriesConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := config.CheckMiddlewareParams([]string{"user_id", "tenant_id"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = config.CheckMiddlewareParams([]string{"user_id"})
+	if err == nil || !strings.Contains(err.Error(), "queries.yaml:7:15: query get_profile declares middleware_params 'tenant_id' that no configured middleware produces (produced: user_id)") {
+		t.Errorf("expected error for a parameter no middleware produces, got %v", err)
+	}
+}
@@ -3,12 +3,13 @@ package db
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
-	"log"
+	"log/slog"
 	"sync/atomic"
 	"time"
 
-	_ "github.com/lib/pq" // PostgreSQL driver
+	"github.com/lib/pq"
 	"github.com/shogotsuneto/simple-query-server/internal/config"
 )
 
@@ -27,6 +28,9 @@ type PostgreSQLManager struct {
 	db       *sql.DB
 	healthy  int64              // atomic boolean for health status
 	cancel   context.CancelFunc // for stopping the health check goroutine
+
+	connected  bool         // whether a connection was ever established
+	reconnects atomic.Int64 // connections established after the first one
 }
 
 // NewPostgreSQLManager creates a new PostgreSQL database connection manager
@@ -49,6 +53,20 @@ func (m *PostgreSQLManager) GetConnection() *sql.DB {
 	return m.db
 }
 
+// Stats returns the connection pool statistics of the current connection
+func (m *PostgreSQLManager) Stats() sql.DBStats {
+	db := m.db
+	if db == nil {
+		return sql.DBStats{}
+	}
+	return db.Stats()
+}
+
+// Reconnects returns the number of times the connection was re-established after it was lost
+func (m *PostgreSQLManager) Reconnects() int64 {
+	return m.reconnects.Load()
+}
+
 // IsHealthy returns the cached health status
 func (m *PostgreSQLManager) IsHealthy() bool {
 	return atomic.LoadInt64(&m.healthy) == 1
@@ -93,14 +111,14 @@ func (m *PostgreSQLManager) connectionManager(ctx context.Context) {
 func (m *PostgreSQLManager) tryConnect() {
 	delay := baseDelay
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		log.Printf("Attempting database connection (attempt %d/%d)...", attempt, maxRetries)
+		slog.Info("Attempting database connection", "attempt", attempt, "max_attempts", maxRetries)
 
 		if err := m.connect(); err != nil {
-			log.Printf("Database connection attempt %d failed: %v", attempt, err)
+			slog.Warn("Database connection attempt failed", "attempt", attempt, "error", err)
 			atomic.StoreInt64(&m.healthy, 0) // unhealthy
 
 			if attempt < maxRetries {
-				log.Printf("Retrying in %v...", delay)
+				slog.Info("Retrying database connection", "delay", delay)
 				time.Sleep(delay)
 				// Exponential backoff with max delay
 				delay = delay * 2
@@ -108,10 +126,10 @@ func (m *PostgreSQLManager) tryConnect() {
 					delay = maxDelay
 				}
 			} else {
-				log.Printf("Failed to connect after %d attempts, will retry during health checks", maxRetries)
+				slog.Error("Failed to connect to the database, will retry during health checks", "attempts", maxRetries)
 			}
 		} else {
-			log.Printf("Database connection established successfully on attempt %d", attempt)
+			slog.Info("Database connection established", "attempt", attempt)
 			atomic.StoreInt64(&m.healthy, 1) // healthy
 			return
 		}
@@ -120,8 +138,12 @@ func (m *PostgreSQLManager) tryConnect() {
 
 // connect establishes a connection to the database
 func (m *PostgreSQLManager) connect() error {
-	var err error
-	db, err := sql.Open("postgres", m.dbConfig.DSN)
+	// The DSN is read on every attempt, so rotated credentials in a DSN file are picked up
+	dsn, err := m.dbConfig.ReadDSN()
+	if err != nil {
+		return err
+	}
+	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return fmt.Errorf("failed to open PostgreSQL connection: %w", err)
 	}
@@ -138,7 +160,11 @@ func (m *PostgreSQLManager) connect() error {
 	}
 
 	m.db = db
-	log.Printf("Successfully connected to PostgreSQL database")
+	if m.connected {
+		m.reconnects.Add(1)
+	}
+	m.connected = true
+	slog.Info("Successfully connected to PostgreSQL database")
 	return nil
 }
 
@@ -152,7 +178,7 @@ func (m *PostgreSQLManager) performHealthCheck() {
 
 	// Quick ping to verify connection is still alive
 	if err := m.db.Ping(); err != nil {
-		log.Printf("Database health check failed: %v", err)
+		slog.Error("Database health check failed", "error", err)
 		m.db.Close()
 		m.db = nil
 		atomic.StoreInt64(&m.healthy, 0) // unhealthy
@@ -162,3 +188,32 @@ func (m *PostgreSQLManager) performHealthCheck() {
 		atomic.StoreInt64(&m.healthy, 1) // healthy
 	}
 }
+
+// dsnConnector opens PostgreSQL connections with the DSN of a database config, read again
+// for every connection
+type dsnConnector struct {
+	dbConfig *config.DatabaseConfig
+}
+
+// NewConnector returns a connector for sql.OpenDB that reads the DSN of the database config
+// for every new connection, so rotated credentials in a DSN file are picked up when a pool
+// reconnects
+func NewConnector(dbConfig *config.DatabaseConfig) driver.Connector {
+	return &dsnConnector{dbConfig: dbConfig}
+}
+
+func (c *dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	dsn, err := c.dbConfig.ReadDSN()
+	if err != nil {
+		return nil, err
+	}
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
@@ -0,0 +1,217 @@
+package db
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+)
+
+const (
+	// Reconnect backoff of the dedicated LISTEN connection
+	listenerMinReconnect = 1 * time.Second
+	listenerMaxReconnect = 30 * time.Second
+	// Interval for pinging the LISTEN connection to detect connection loss early
+	listenerPingInterval = 90 * time.Second
+)
+
+// Listener holds a dedicated connection that LISTENs on a fixed set of channels and
+// fans notifications out to subscribers
+type Listener struct {
+	dbConfig *config.DatabaseConfig
+	channels []string
+	failed   chan struct{} // signalled when a connection attempt fails
+
+	mu          sync.Mutex
+	listener    *pq.Listener
+	dsn         string                              // DSN the current listener connects with
+	subscribers map[string]map[chan string]struct{} // channel name -> subscriber channels
+	done        chan struct{}
+}
+
+// NewListener creates a listener for the given channels. The connection is established
+// in the background and re-established automatically after connection loss. When a
+// connection attempt fails, the DSN is read again, so rotated credentials in a DSN file are
+// picked up.
+func NewListener(dbConfig *config.DatabaseConfig, channels []string) *Listener {
+	l := &Listener{
+		dbConfig:    dbConfig,
+		channels:    channels,
+		failed:      make(chan struct{}, 1),
+		subscribers: make(map[string]map[chan string]struct{}),
+		done:        make(chan struct{}),
+	}
+
+	l.connect(dbConfig.DSN, false)
+	go l.dispatch()
+
+	return l
+}
+
+// connect replaces the pq listener with one connecting with the given DSN and LISTENs on
+// all channels in the background. Must be called with mu held or before the listener is
+// shared.
+func (l *Listener) connect(dsn string, replaced bool) {
+	listener := pq.NewListener(dsn, listenerMinReconnect, listenerMaxReconnect, func(event pq.ListenerEventType, err error) {
+		switch event {
+		case pq.ListenerEventConnected:
+			slog.Info("Notification listener connected")
+			if replaced {
+				// Notifications sent while the previous DSN failed were missed
+				l.notifyAll()
+			}
+		case pq.ListenerEventDisconnected:
+			slog.Warn("Notification listener disconnected", "error", err)
+		case pq.ListenerEventReconnected:
+			slog.Info("Notification listener reconnected")
+		case pq.ListenerEventConnectionAttemptFailed:
+			slog.Warn("Notification listener connection attempt failed", "error", err)
+			select {
+			case l.failed <- struct{}{}:
+			default:
+			}
+		}
+	})
+
+	l.listener = listener
+	l.dsn = dsn
+	go listen(listener, l.channels)
+}
+
+// Subscribe registers for notifications on the given channels. The returned channel receives
+// the name of the notified channel, or an empty string when notifications may have been missed
+// during a reconnect. Notifications are coalesced while the subscriber is busy. The returned
+// function cancels the subscription.
+func (l *Listener) Subscribe(channels []string) (<-chan string, func()) {
+	notifications := make(chan string, 1)
+
+	l.mu.Lock()
+	for _, channel := range channels {
+		if l.subscribers[channel] == nil {
+			l.subscribers[channel] = make(map[chan string]struct{})
+		}
+		l.subscribers[channel][notifications] = struct{}{}
+	}
+	l.mu.Unlock()
+
+	cancel := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for _, channel := range channels {
+			delete(l.subscribers[channel], notifications)
+		}
+	}
+
+	return notifications, cancel
+}
+
+// Close closes the LISTEN connection
+func (l *Listener) Close() error {
+	close(l.done)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.listener.Close()
+}
+
+// listen issues a LISTEN for every channel. Listen blocks until the connection is
+// available, so this runs in the background.
+func listen(listener *pq.Listener, channels []string) {
+	for _, channel := range channels {
+		if err := listener.Listen(channel); err != nil {
+			slog.Error("Failed to listen on channel", "channel", channel, "error", err)
+			continue
+		}
+		slog.Info("Listening for notifications", "channel", channel)
+	}
+}
+
+// dispatch forwards notifications to the subscribers of their channel and replaces the
+// connection when the DSN changed after a failed connection attempt
+func (l *Listener) dispatch() {
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+
+	l.mu.Lock()
+	listener := l.listener
+	l.mu.Unlock()
+
+	for {
+		select {
+		case <-l.done:
+			return
+		case notification, ok := <-listener.Notify:
+			if !ok {
+				return
+			}
+			if notification == nil {
+				// The connection was re-established; notifications may have been lost
+				l.notifyAll()
+				continue
+			}
+			l.notify(notification.Channel)
+		case <-l.failed:
+			if replaced := l.reconnect(); replaced != nil {
+				listener = replaced
+			}
+		case <-ticker.C:
+			go listener.Ping()
+		}
+	}
+}
+
+// reconnect reads the DSN again and, if it changed, replaces the pq listener, which keeps
+// retrying with the DSN it was created with. It returns the new listener, or nil.
+func (l *Listener) reconnect() *pq.Listener {
+	dsn, err := l.dbConfig.ReadDSN()
+	if err != nil {
+		slog.Warn("Failed to read the DSN for the notification listener", "error", err)
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-l.done:
+		return nil
+	default:
+	}
+	if dsn == l.dsn {
+		return nil
+	}
+
+	slog.Info("DSN changed, reconnecting the notification listener")
+	previous := l.listener
+	l.connect(dsn, true)
+	go previous.Close()
+	return l.listener
+}
+
+// notify signals all subscribers of a channel without blocking
+func (l *Listener) notify(channel string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for subscriber := range l.subscribers[channel] {
+		signal(subscriber, channel)
+	}
+}
+
+// notifyAll signals every subscriber that notifications may have been missed
+func (l *Listener) notifyAll() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, subscribers := range l.subscribers {
+		for subscriber := range subscribers {
+			signal(subscriber, "")
+		}
+	}
+}
+
+// signal sends to a subscriber unless a notification is already pending
+func signal(subscriber chan string, channel string) {
+	select {
+	case subscriber <- channel:
+	default:
+	}
+}
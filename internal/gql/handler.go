@@ -0,0 +1,130 @@
+package gql
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+// Request represents a GraphQL request as sent by clients over HTTP
+type Request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+}
+
+// Handler serves GraphQL requests against a schema generated from the query definitions.
+// The schema needs column metadata from the database, so it is built on the first
+// request once the database is available.
+type Handler struct {
+	executor query.QueryExecutor
+
+	schemaMutex   sync.Mutex
+	queriesConfig *config.QueriesConfig
+	schema        *graphql.Schema
+}
+
+// NewHandler creates a new GraphQL handler
+func NewHandler(queriesConfig *config.QueriesConfig, executor query.QueryExecutor) *Handler {
+	return &Handler{
+		queriesConfig: queriesConfig,
+		executor:      executor,
+	}
+}
+
+// Update replaces the query definitions, e.g. after a configuration reload. The schema
+// is rebuilt on the next request.
+func (h *Handler) Update(queriesConfig *config.QueriesConfig) {
+	h.schemaMutex.Lock()
+	defer h.schemaMutex.Unlock()
+
+	h.queriesConfig = queriesConfig
+	h.schema = nil
+}
+
+// ServeHTTP handles GraphQL queries sent as GET query string or POST JSON body
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request Request
+	switch r.Method {
+	case http.MethodGet:
+		request.Query = r.URL.Query().Get("query")
+		request.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				writeError(w, "Invalid JSON in variables", http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, "Invalid JSON in request body", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if request.Query == "" {
+		writeError(w, "GraphQL query is required", http.StatusBadRequest)
+		return
+	}
+
+	schema, err := h.getSchema(r)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "GraphQL schema error", "error", err)
+		writeError(w, "GraphQL schema is not available: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         *schema,
+		RequestString:  request.Query,
+		VariableValues: request.Variables,
+		OperationName:  request.OperationName,
+		Context:        r.Context(),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// getSchema returns the schema, building it if this has not succeeded yet
+func (h *Handler) getSchema(r *http.Request) (*graphql.Schema, error) {
+	h.schemaMutex.Lock()
+	defer h.schemaMutex.Unlock()
+
+	if h.schema != nil {
+		return h.schema, nil
+	}
+
+	// Without a database connection no query could be described, so retry on a later request
+	if !h.executor.IsHealthy() {
+		return nil, errDatabaseUnavailable
+	}
+
+	schema, err := BuildSchema(r.Context(), h.queriesConfig.Queries, h.executor)
+	if err != nil {
+		return nil, err
+	}
+	h.schema = &schema
+	return h.schema, nil
+}
+
+// errDatabaseUnavailable is returned while the schema cannot be built yet
+var errDatabaseUnavailable = errors.New("database connection not available")
+
+// writeError writes an error in the GraphQL response format
+func writeError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]string{{"message": message}},
+	})
+}
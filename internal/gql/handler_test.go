@@ -0,0 +1,130 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+// stubExecutor describes every query with fixed columns and returns the parameters it received
+type stubExecutor struct {
+	healthy bool
+}
+
+func (e *stubExecutor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	return []map[string]interface{}{{"id": params["id"], "tenant": params["tenant_id"], "tags": "a,b"}}, nil
+}
+
+func (e *stubExecutor) Describe(ctx context.Context, queryConfig config.Query) ([]query.Column, error) {
+	return []query.Column{{Name: "id", Type: "INT4"}, {Name: "tenant", Type: "TEXT"}, {Name: "count(*)", Type: "INT8"}}, nil
+}
+
+func (e *stubExecutor) BeginSnapshot(ctx context.Context) (query.Snapshot, error) {
+	return nil, nil
+}
+
+func (e *stubExecutor) Close() error {
+	return nil
+}
+
+func (e *stubExecutor) IsHealthy() bool {
+	return e.healthy
+}
+
+func TestHandler(t *testing.T) {
+	queriesConfig := &config.QueriesConfig{Queries: map[string]config.Query{
+		"get_user": {
+			SQL:              "SELECT id, tenant FROM users WHERE id = :id AND tenant = :tenant_id",
+			Params:           []config.QueryParam{{Name: "id", Type: "int"}},
+			MiddlewareParams: []config.QueryParam{{Name: "tenant_id", Type: "string"}},
+		},
+		"invalid-name": {SQL: "SELECT 1"},
+	}}
+
+	// doRequest sends a GraphQL query with middleware parameters in the request context
+	doRequest := func(handler *Handler, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+		req = middleware.SetMiddlewareParams(req, map[string]interface{}{"tenant_id": "t1"})
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var response map[string]interface{}
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		return rr, response
+	}
+
+	t.Run("ExecutesQueryWithMiddlewareParams", func(t *testing.T) {
+		handler := NewHandler(queriesConfig, &stubExecutor{healthy: true})
+		rr, response := doRequest(handler, `{"query": "query($id: Int!) { get_user(id: $id) { id tenant } }", "variables": {"id": 5}}`)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if response["errors"] != nil {
+			t.Fatalf("unexpected errors: %v", response["errors"])
+		}
+
+		rows := response["data"].(map[string]interface{})["get_user"].([]interface{})
+		row := rows[0].(map[string]interface{})
+		if row["id"] != float64(5) || row["tenant"] != "t1" {
+			t.Errorf("unexpected row: %v", row)
+		}
+	})
+
+	t.Run("SupportsIntrospection", func(t *testing.T) {
+		handler := NewHandler(queriesConfig, &stubExecutor{healthy: true})
+		_, response := doRequest(handler, `{"query": "{ __schema { queryType { fields { name args { name } } } mutationType { name } } }"}`)
+
+		schema := response["data"].(map[string]interface{})["__schema"].(map[string]interface{})
+		if schema["mutationType"] != nil {
+			t.Errorf("expected no mutation type, got %v", schema["mutationType"])
+		}
+
+		fields := schema["queryType"].(map[string]interface{})["fields"].([]interface{})
+		if len(fields) != 1 {
+			t.Fatalf("expected only the valid query to be exposed, got %v", fields)
+		}
+		args := fields[0].(map[string]interface{})["args"].([]interface{})
+		if len(args) != 1 || args[0].(map[string]interface{})["name"] != "id" {
+			t.Errorf("expected only body parameters as arguments, got %v", args)
+		}
+	})
+
+	t.Run("UnavailableWithoutDatabase", func(t *testing.T) {
+		handler := NewHandler(queriesConfig, &stubExecutor{healthy: false})
+		rr, _ := doRequest(handler, `{"query": "{ get_user(id: 1) { id } }"}`)
+
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status 503, got %d", rr.Code)
+		}
+	})
+
+	t.Run("RequiresQuery", func(t *testing.T) {
+		handler := NewHandler(queriesConfig, &stubExecutor{healthy: true})
+		rr, _ := doRequest(handler, `{}`)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", rr.Code)
+		}
+	})
+}
+
+func TestTypeName(t *testing.T) {
+	tests := map[string]string{
+		"get_user_by_id": "GetUserById",
+		"users":          "Users",
+		"_private":       "Private",
+	}
+	for input, expected := range tests {
+		if got := typeName(input); got != expected {
+			t.Errorf("typeName(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package gql
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+// namePattern matches valid GraphQL names
+var namePattern = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// BuildSchema creates a read-only GraphQL schema with one root Query field per query.
+// Field arguments are derived from the query's body parameters and the row object type
+// from the result columns reported by the executor. Queries that cannot be described
+// are left out of the schema.
+func BuildSchema(ctx context.Context, queries map[string]config.Query, executor query.QueryExecutor) (graphql.Schema, error) {
+	names := make([]string, 0, len(queries))
+	for name := range queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := graphql.Fields{}
+	typeNames := make(map[string]bool)
+	for _, name := range names {
+		if !namePattern.MatchString(name) {
+			slog.Warn("GraphQL: skipping query: not a valid GraphQL field name", "query", name)
+			continue
+		}
+
+		queryConfig := queries[name]
+		columns, err := executor.Describe(ctx, queryConfig)
+		if err != nil {
+			slog.Warn("GraphQL: skipping query", "query", name, "error", err)
+			continue
+		}
+
+		if !hasValidColumn(columns) {
+			slog.Warn("GraphQL: skipping query: no columns with valid GraphQL field names", "query", name)
+			continue
+		}
+
+		// Type names must be unique, but distinct query names can map to the same PascalCase name
+		rowTypeName := typeName(name) + "Row"
+		for i := 2; typeNames[rowTypeName]; i++ {
+			rowTypeName = fmt.Sprintf("%sRow%d", typeName(name), i)
+		}
+		typeNames[rowTypeName] = true
+
+		fields[name] = &graphql.Field{
+			Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(rowType(name, rowTypeName, columns)))),
+			Args:    fieldArguments(queryConfig),
+			Resolve: resolver(queryConfig, executor),
+		}
+	}
+
+	if len(fields) == 0 {
+		return graphql.Schema{}, fmt.Errorf("no queries could be exposed through GraphQL")
+	}
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name:   "Query",
+			Fields: fields,
+		}),
+	})
+}
+
+// rowType creates the object type of a result row from the query's columns
+func rowType(queryName string, rowTypeName string, columns []query.Column) *graphql.Object {
+	fields := graphql.Fields{}
+	for _, column := range columns {
+		if !namePattern.MatchString(column.Name) {
+			slog.Warn("GraphQL: skipping column: not a valid GraphQL field name", "column", column.Name, "query", queryName)
+			continue
+		}
+		fields[column.Name] = &graphql.Field{Type: columnType(column.Type)}
+	}
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:   rowTypeName,
+		Fields: fields,
+	})
+}
+
+// hasValidColumn reports whether at least one column can be exposed as a GraphQL field
+func hasValidColumn(columns []query.Column) bool {
+	for _, column := range columns {
+		if namePattern.MatchString(column.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// columnType maps a PostgreSQL type name to a GraphQL scalar
+func columnType(databaseType string) graphql.Output {
+	switch strings.ToUpper(databaseType) {
+	case "INT2", "INT4":
+		return graphql.Int
+	case "INT8", "NUMERIC", "FLOAT4", "FLOAT8":
+		// GraphQL Int is 32-bit, so 64-bit integers are exposed as Float
+		return graphql.Float
+	case "BOOL":
+		return graphql.Boolean
+	case "DATE", "TIMESTAMP", "TIMESTAMPTZ":
+		return graphql.DateTime
+	default:
+		return graphql.String
+	}
+}
+
+// fieldArguments creates the field arguments from the query's body parameters.
+// Middleware parameters are injected from the request context and not exposed.
+func fieldArguments(queryConfig config.Query) graphql.FieldConfigArgument {
+	args := graphql.FieldConfigArgument{}
+	for _, param := range queryConfig.Params {
+		var argType graphql.Input
+		switch param.ElementType() {
+		case "int":
+			argType = graphql.Int
+		case "float":
+			argType = graphql.Float
+		default:
+			argType = graphql.String
+		}
+		if param.IsArray() {
+			argType = graphql.NewList(graphql.NewNonNull(argType))
+		}
+		args[param.Name] = &graphql.ArgumentConfig{Type: graphql.NewNonNull(argType)}
+	}
+	return args
+}
+
+// resolver executes the query with the field arguments and the middleware parameters of the request
+func resolver(queryConfig config.Query, executor query.QueryExecutor) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		params := make(map[string]interface{})
+		for k, v := range p.Args {
+			params[k] = v
+		}
+		for k, v := range middleware.ParamsFromContext(p.Context) {
+			params[k] = v
+		}
+
+		rows, err := executor.Execute(p.Context, queryConfig, params)
+		if err != nil {
+			return nil, err
+		}
+		if rows == nil {
+			rows = []map[string]interface{}{}
+		}
+		return rows, nil
+	}
+}
+
+// typeName converts a snake_case query name into a PascalCase type name
+func typeName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package grpcserver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/shogotsuneto/simple-query-server/internal/logging"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// paramsInterceptor applies the middleware chain to incoming call metadata and
+// stores the extracted parameters in the call context
+type paramsInterceptor struct {
+	chain atomic.Pointer[middleware.Chain]
+}
+
+// unary intercepts unary calls
+func (i *paramsInterceptor) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if isHealthMethod(info.FullMethod) {
+		return handler(ctx, req)
+	}
+
+	ctx, err := i.contextWithParams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// stream intercepts server streaming calls
+func (i *paramsInterceptor) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if isHealthMethod(info.FullMethod) {
+		return handler(srv, ss)
+	}
+
+	ctx, err := i.contextWithParams(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
+
+// contextWithParams extracts middleware parameters from the call metadata, and assigns the
+// call the request id from its x-request-id metadata or a new one
+func (i *paramsInterceptor) contextWithParams(ctx context.Context) (context.Context, error) {
+	// Metadata keys are lower-case; http.Header canonicalizes them for the middleware lookups
+	header := make(http.Header)
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
+
+	requestID := header.Get(logging.RequestIDHeader)
+	if !logging.ValidRequestID(requestID) {
+		requestID = logging.NewRequestID()
+	}
+	ctx = logging.WithRequestID(ctx, requestID)
+
+	params, err := i.chain.Load().ExtractParams(header)
+	if err != nil {
+		if rejection, ok := err.(*middleware.Rejection); ok {
+			return nil, status.Error(statusCode(rejection.StatusCode), rejection.Message)
+		}
+		return nil, status.Error(statusCode(http.StatusInternalServerError), err.Error())
+	}
+
+	return middleware.ContextWithParams(ctx, params), nil
+}
+
+// isHealthMethod reports whether the method belongs to the health service, which requires no authentication
+func isHealthMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/"+healthpb.Health_ServiceDesc.ServiceName+"/")
+}
+
+// contextStream overrides the context of a server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,213 @@
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	queryserverv1 "github.com/shogotsuneto/simple-query-server/api/queryserver/v1"
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// healthUpdateInterval is how often the health service status is refreshed from the executor
+const healthUpdateInterval = 5 * time.Second
+
+// Server exposes query execution over gRPC
+type Server struct {
+	queryserverv1.UnimplementedQueryServiceServer
+
+	queriesConfig atomic.Pointer[config.QueriesConfig]
+	executor      query.QueryExecutor
+	interceptor   *paramsInterceptor
+	grpcServer    *grpc.Server
+	healthServer  *health.Server
+}
+
+// New creates a gRPC server that injects middleware parameters from call metadata
+// using the ParamExtractor middleware of the chain
+func New(queriesConfig *config.QueriesConfig, executor query.QueryExecutor, chain middleware.Chain) *Server {
+	warnUnsupportedMiddleware(chain)
+
+	interceptor := &paramsInterceptor{}
+	interceptor.chain.Store(&chain)
+	s := &Server{
+		executor:    executor,
+		interceptor: interceptor,
+		grpcServer: grpc.NewServer(
+			grpc.ChainUnaryInterceptor(interceptor.unary),
+			grpc.ChainStreamInterceptor(interceptor.stream),
+		),
+		healthServer: health.NewServer(),
+	}
+
+	s.queriesConfig.Store(queriesConfig)
+
+	queryserverv1.RegisterQueryServiceServer(s.grpcServer, s)
+	healthpb.RegisterHealthServer(s.grpcServer, s.healthServer)
+
+	return s
+}
+
+// Serve accepts connections on the listener until Stop is called
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	go s.updateHealth(ctx)
+	return s.grpcServer.Serve(listener)
+}
+
+// Stop gracefully stops the server, waiting for in-flight calls to finish
+func (s *Server) Stop() {
+	s.healthServer.Shutdown()
+	s.grpcServer.GracefulStop()
+}
+
+// updateHealth keeps the health service status in line with the database health
+func (s *Server) updateHealth(ctx context.Context) {
+	ticker := time.NewTicker(healthUpdateInterval)
+	defer ticker.Stop()
+
+	for {
+		servingStatus := healthpb.HealthCheckResponse_NOT_SERVING
+		if s.executor.IsHealthy() {
+			servingStatus = healthpb.HealthCheckResponse_SERVING
+		}
+		s.healthServer.SetServingStatus("", servingStatus)
+		s.healthServer.SetServingStatus(queryserverv1.QueryService_ServiceDesc.ServiceName, servingStatus)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExecuteQuery executes a named query and streams each result row back as a Struct
+func (s *Server) ExecuteQuery(request *queryserverv1.ExecuteQueryRequest, stream queryserverv1.QueryService_ExecuteQueryServer) error {
+	queryConfig, exists := s.queriesConfig.Load().Queries[request.GetName()]
+	if !exists {
+		return status.Errorf(codes.NotFound, "query '%s' not found", request.GetName())
+	}
+
+	// Only parameters defined in the YAML are taken from the request
+	requestParams := request.GetParams().AsMap()
+	params := make(map[string]interface{})
+	for _, param := range queryConfig.Params {
+		if value, exists := requestParams[param.Name]; exists {
+			params[param.Name] = value
+		}
+	}
+	for k, v := range middleware.ParamsFromContext(stream.Context()) {
+		params[k] = v
+	}
+
+	rows, err := s.executor.Execute(stream.Context(), queryConfig, params)
+	if err != nil {
+		slog.ErrorContext(stream.Context(), "gRPC query execution error", "query", request.GetName(), "error", err)
+		if query.IsClientError(err) {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	for _, row := range rows {
+		rowStruct, err := structpb.NewStruct(structValues(row))
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to convert row: %v", err)
+		}
+		if err := stream.Send(rowStruct); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Update replaces the query definitions, e.g. after a configuration reload
+func (s *Server) Update(queriesConfig *config.QueriesConfig) {
+	s.queriesConfig.Store(queriesConfig)
+}
+
+// UpdateChain replaces the middleware chain applied to calls, e.g. after a configuration reload
+func (s *Server) UpdateChain(chain middleware.Chain) {
+	warnUnsupportedMiddleware(chain)
+	s.interceptor.chain.Store(&chain)
+}
+
+// warnUnsupportedMiddleware logs the middleware of the chain that cannot be applied to calls
+func warnUnsupportedMiddleware(chain middleware.Chain) {
+	for _, mw := range chain {
+		if _, ok := mw.(middleware.ParamExtractor); !ok {
+			slog.Warn("gRPC: middleware does not support gRPC metadata and is skipped", "middleware", mw.Name())
+		}
+	}
+}
+
+// ListQueries lists the available queries sorted by name
+func (s *Server) ListQueries(ctx context.Context, request *queryserverv1.ListQueriesRequest) (*queryserverv1.ListQueriesResponse, error) {
+	queriesConfig := s.queriesConfig.Load()
+	names := make([]string, 0, len(queriesConfig.Queries))
+	for name := range queriesConfig.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	response := &queryserverv1.ListQueriesResponse{}
+	for _, name := range names {
+		queryConfig := queriesConfig.Queries[name]
+		response.Queries = append(response.Queries, &queryserverv1.QueryInfo{
+			Name:             name,
+			Sql:              queryConfig.SQL,
+			Params:           protoParams(queryConfig.Params),
+			MiddlewareParams: protoParams(queryConfig.MiddlewareParams),
+		})
+	}
+
+	return response, nil
+}
+
+// protoParams converts parameter definitions to their protobuf representation
+func protoParams(params []config.QueryParam) []*queryserverv1.QueryParam {
+	result := make([]*queryserverv1.QueryParam, len(params))
+	for i, param := range params {
+		result[i] = &queryserverv1.QueryParam{Name: param.Name, Type: param.Type}
+	}
+	return result
+}
+
+// structValues converts row values that Struct cannot represent directly
+func structValues(row map[string]interface{}) map[string]interface{} {
+	values := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		if t, ok := v.(time.Time); ok {
+			v = t.Format(time.RFC3339Nano)
+		}
+		values[k] = v
+	}
+	return values
+}
+
+// statusCode maps the HTTP status code of a middleware rejection to a gRPC code
+func statusCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,164 @@
+package grpcserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	queryserverv1 "github.com/shogotsuneto/simple-query-server/api/queryserver/v1"
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// echoExecutor returns one row per element of the "ids" parameter, echoing the tenant
+type echoExecutor struct{}
+
+func (e *echoExecutor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	ids, ok := params["ids"].([]interface{})
+	if !ok {
+		return nil, query.NewClientError("parameter 'ids' must be an array")
+	}
+	rows := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		rows = append(rows, map[string]interface{}{"id": id, "tenant": params["tenant_id"]})
+	}
+	return rows, nil
+}
+
+func (e *echoExecutor) Describe(ctx context.Context, queryConfig config.Query) ([]query.Column, error) {
+	return nil, nil
+}
+
+func (e *echoExecutor) BeginSnapshot(ctx context.Context) (query.Snapshot, error) {
+	return nil, nil
+}
+
+func (e *echoExecutor) Close() error {
+	return nil
+}
+
+func (e *echoExecutor) IsHealthy() bool {
+	return true
+}
+
+// startTestServer starts the gRPC server on an in-memory listener and returns a client connection
+func startTestServer(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+
+	queriesConfig := &config.QueriesConfig{Queries: map[string]config.Query{
+		"list_items": {
+			SQL:              "SELECT * FROM items WHERE id = ANY(:ids) AND tenant_id = :tenant_id",
+			Params:           []config.QueryParam{{Name: "ids", Type: "int[]"}},
+			MiddlewareParams: []config.QueryParam{{Name: "tenant_id", Type: "string"}},
+		},
+	}}
+	chain := middleware.Chain{middleware.NewHTTPHeaderMiddleware(middleware.HTTPHeaderConfig{
+		Header:    "X-Tenant-ID",
+		Parameter: "tenant_id",
+		Required:  true,
+	})}
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := New(queriesConfig, &echoExecutor{}, chain)
+	ctx, cancel := context.WithCancel(context.Background())
+	go server.Serve(ctx, listener)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		cancel()
+		server.Stop()
+	})
+	return conn
+}
+
+func TestExecuteQuery(t *testing.T) {
+	client := queryserverv1.NewQueryServiceClient(startTestServer(t))
+	params, _ := structpb.NewStruct(map[string]interface{}{"ids": []interface{}{1, 2}, "ignored": "x"})
+
+	t.Run("StreamsRowsWithMetadataParams", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant-id", "t1")
+		stream, err := client.ExecuteQuery(ctx, &queryserverv1.ExecuteQueryRequest{Name: "list_items", Params: params})
+		if err != nil {
+			t.Fatalf("failed to execute query: %v", err)
+		}
+
+		var rows []*structpb.Struct
+		for {
+			row, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("failed to receive row: %v", err)
+			}
+			rows = append(rows, row)
+		}
+
+		if len(rows) != 2 {
+			t.Fatalf("expected 2 rows, got %d", len(rows))
+		}
+		if tenant := rows[0].AsMap()["tenant"]; tenant != "t1" {
+			t.Errorf("expected tenant from metadata, got %v", tenant)
+		}
+	})
+
+	t.Run("RejectsMissingMetadata", func(t *testing.T) {
+		stream, err := client.ExecuteQuery(context.Background(), &queryserverv1.ExecuteQueryRequest{Name: "list_items", Params: params})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected InvalidArgument, got %v", err)
+		}
+	})
+
+	t.Run("UnknownQuery", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant-id", "t1")
+		stream, err := client.ExecuteQuery(ctx, &queryserverv1.ExecuteQueryRequest{Name: "unknown"})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("expected NotFound, got %v", err)
+		}
+	})
+}
+
+func TestListQueriesAndHealth(t *testing.T) {
+	conn := startTestServer(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant-id", "t1")
+
+	response, err := queryserverv1.NewQueryServiceClient(conn).ListQueries(ctx, &queryserverv1.ListQueriesRequest{})
+	if err != nil {
+		t.Fatalf("failed to list queries: %v", err)
+	}
+	if len(response.Queries) != 1 || response.Queries[0].Name != "list_items" {
+		t.Errorf("unexpected queries: %v", response.Queries)
+	}
+
+	// The health service is available without the metadata required by the middleware
+	health, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("failed to check health: %v", err)
+	}
+	if health.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("expected SERVING, got %v", health.Status)
+	}
+}
@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// WriteCSV converts a spooled newline-delimited JSON result into CSV with a header row
+func WriteCSV(w io.Writer, r io.Reader, columns []string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	decoder.UseNumber()
+	record := make([]string, len(columns))
+	for {
+		var row map[string]interface{}
+		if err := decoder.Decode(&row); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read job result: %w", err)
+		}
+
+		for i, column := range columns {
+			record[i] = csvValue(row[column])
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvValue formats a decoded JSON value as a CSV field
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		// Arrays and objects are written as JSON
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
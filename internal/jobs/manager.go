@@ -0,0 +1,421 @@
+// Package jobs executes long-running queries asynchronously and spools their results to disk
+package jobs
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+const (
+	// Defaults for jobs when not set in the server configuration
+	defaultWorkers   = 2
+	defaultMaxQueued = 100
+	defaultTTL       = 1 * time.Hour
+
+	// Interval for removing expired jobs
+	cleanupInterval = 1 * time.Minute
+)
+
+// Job states
+const (
+	StateQueued    = "queued"
+	StateRunning   = "running"
+	StateCompleted = "completed"
+	StateFailed    = "failed"
+	StateCancelled = "cancelled"
+)
+
+var (
+	// ErrNotFound is returned for unknown or expired jobs
+	ErrNotFound = errors.New("job not found")
+	// ErrQueueFull is returned when no more jobs can be queued
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrNotCompleted is returned when the result of an unfinished or failed job is requested
+	ErrNotCompleted = errors.New("job has not completed")
+)
+
+// Status describes the state and progress of a job
+type Status struct {
+	ID         string     `json:"id"`
+	Query      string     `json:"query"`
+	State      string     `json:"state"`
+	Rows       int64      `json:"rows"` // Number of result rows spooled so far
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// job is a single asynchronous query execution
+type job struct {
+	id               string
+	queryName        string
+	queryConfig      config.Query
+	params           map[string]interface{}
+	middlewareParams map[string]interface{} // identify the owner of the job
+	ctx              context.Context
+	cancel           context.CancelFunc
+	rows             atomic.Int64
+
+	// Guarded by Manager.mu
+	state      string
+	err        string
+	columns    []string
+	createdAt  time.Time
+	startedAt  time.Time
+	finishedAt time.Time
+}
+
+// Manager runs jobs on a bounded worker pool and keeps finished jobs until they expire
+type Manager struct {
+	executor  query.QueryExecutor
+	directory string
+	ttl       time.Duration
+
+	mu    sync.Mutex
+	jobs  map[string]*job
+	queue chan *job
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewManager creates a job manager and starts its workers
+func NewManager(jobsConfig config.JobsConfig, executor query.QueryExecutor) (*Manager, error) {
+	if jobsConfig.Workers == 0 {
+		jobsConfig.Workers = defaultWorkers
+	}
+	if jobsConfig.MaxQueued == 0 {
+		jobsConfig.MaxQueued = defaultMaxQueued
+	}
+	if jobsConfig.TTL == 0 {
+		jobsConfig.TTL = defaultTTL
+	}
+	if jobsConfig.Directory == "" {
+		jobsConfig.Directory = filepath.Join(os.TempDir(), "simple-query-server-jobs")
+	}
+
+	if err := os.MkdirAll(jobsConfig.Directory, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create jobs directory: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		executor:  executor,
+		directory: jobsConfig.Directory,
+		ttl:       jobsConfig.TTL,
+		jobs:      make(map[string]*job),
+		queue:     make(chan *job, jobsConfig.MaxQueued),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+
+	for i := 0; i < jobsConfig.Workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+	m.wg.Add(1)
+	go m.cleanup()
+
+	return m, nil
+}
+
+// Submit queues a query execution. The middleware parameters are part of the query
+// parameters and also restrict access to the job to requests with the same values.
+func (m *Manager) Submit(queryName string, queryConfig config.Query, params map[string]interface{}, middlewareParams map[string]interface{}) (Status, error) {
+	id, err := newID()
+	if err != nil {
+		return Status{}, err
+	}
+
+	// The middleware parameters identify the owner of the execution, e.g. in the audit log
+	ctx, cancel := context.WithCancel(middleware.ContextWithParams(m.ctx, middlewareParams))
+	j := &job{
+		id:               id,
+		queryName:        queryName,
+		queryConfig:      queryConfig,
+		params:           params,
+		middlewareParams: middlewareParams,
+		ctx:              ctx,
+		cancel:           cancel,
+		state:            StateQueued,
+		createdAt:        time.Now(),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	select {
+	case m.queue <- j:
+	default:
+		cancel()
+		return Status{}, ErrQueueFull
+	}
+	m.jobs[id] = j
+
+	return m.status(j), nil
+}
+
+// Get returns the status of a job owned by the given middleware parameters
+func (m *Manager) Get(id string, middlewareParams map[string]interface{}) (Status, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, err := m.lookup(id, middlewareParams)
+	if err != nil {
+		return Status{}, err
+	}
+	return m.status(j), nil
+}
+
+// Cancel cancels a queued or running job. Finished jobs are removed together with their result.
+func (m *Manager) Cancel(id string, middlewareParams map[string]interface{}) (Status, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, err := m.lookup(id, middlewareParams)
+	if err != nil {
+		return Status{}, err
+	}
+
+	switch j.state {
+	case StateQueued:
+		// The worker skips jobs that were cancelled while queued
+		j.state = StateCancelled
+		j.finishedAt = time.Now()
+	case StateRunning:
+		// The worker records the cancellation once the execution has stopped
+	default:
+		m.remove(j)
+	}
+	j.cancel()
+
+	return m.status(j), nil
+}
+
+// Result opens the spooled result of a completed job. Rows are stored as newline-delimited
+// JSON; the column order of the query is returned alongside.
+func (m *Manager) Result(id string, middlewareParams map[string]interface{}) (*os.File, []string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, err := m.lookup(id, middlewareParams)
+	if err != nil {
+		return nil, nil, err
+	}
+	if j.state != StateCompleted {
+		return nil, nil, ErrNotCompleted
+	}
+
+	file, err := os.Open(m.resultPath(j))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open job result: %w", err)
+	}
+	return file, j.columns, nil
+}
+
+// Close cancels all jobs, waits for the workers to stop and removes the spooled results
+func (m *Manager) Close() error {
+	m.cancel()
+	m.wg.Wait()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, j := range m.jobs {
+		m.remove(j)
+	}
+	return nil
+}
+
+// lookup finds a job by ID. Jobs of other owners are reported as not found.
+func (m *Manager) lookup(id string, middlewareParams map[string]interface{}) (*job, error) {
+	j, exists := m.jobs[id]
+	if !exists || !sameOwner(j.middlewareParams, middlewareParams) {
+		return nil, ErrNotFound
+	}
+	return j, nil
+}
+
+// sameOwner compares middleware parameters, treating nil and empty maps as equal
+func sameOwner(a, b map[string]interface{}) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// status returns a snapshot of the job's status; m.mu must be held
+func (m *Manager) status(j *job) Status {
+	status := Status{
+		ID:        j.id,
+		Query:     j.queryName,
+		State:     j.state,
+		Rows:      j.rows.Load(),
+		Error:     j.err,
+		CreatedAt: j.createdAt,
+	}
+	if !j.startedAt.IsZero() {
+		startedAt := j.startedAt
+		status.StartedAt = &startedAt
+	}
+	if !j.finishedAt.IsZero() {
+		finishedAt := j.finishedAt
+		expiresAt := j.finishedAt.Add(m.ttl)
+		status.FinishedAt = &finishedAt
+		status.ExpiresAt = &expiresAt
+	}
+	return status
+}
+
+// remove deletes a job and its spooled result; m.mu must be held
+func (m *Manager) remove(j *job) {
+	delete(m.jobs, j.id)
+	if err := os.Remove(m.resultPath(j)); err != nil && !os.IsNotExist(err) {
+		slog.Error("Failed to remove job result", "job", j.id, "error", err)
+	}
+}
+
+// resultPath returns the spool file of a job
+func (m *Manager) resultPath(j *job) string {
+	return filepath.Join(m.directory, j.id+".ndjson")
+}
+
+// worker executes queued jobs until the manager is closed
+func (m *Manager) worker() {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case j := <-m.queue:
+			m.run(j)
+		}
+	}
+}
+
+// run executes a job and spools its result
+func (m *Manager) run(j *job) {
+	m.mu.Lock()
+	if j.state != StateQueued {
+		m.mu.Unlock()
+		return
+	}
+	j.state = StateRunning
+	j.startedAt = time.Now()
+	m.mu.Unlock()
+
+	columns, err := m.execute(j)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j.finishedAt = time.Now()
+	switch {
+	case j.ctx.Err() != nil:
+		j.state = StateCancelled
+	case err != nil:
+		slog.Error("Job failed", "job", j.id, "query", j.queryName, "error", err)
+		j.state = StateFailed
+		j.err = err.Error()
+	default:
+		j.state = StateCompleted
+		j.columns = columns
+	}
+	if j.state != StateCompleted {
+		os.Remove(m.resultPath(j))
+	}
+}
+
+// execute runs the query of a job and writes its rows to the spool file as they are read,
+// so results are never held in memory as a whole and the progress counts rows as they arrive
+func (m *Manager) execute(j *job) ([]string, error) {
+	file, err := os.OpenFile(m.resultPath(j), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job result: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	var columns []string
+	_, err = query.Stream(j.ctx, m.executor, j.queryConfig, j.params,
+		func(resultColumns []string) error {
+			columns = resultColumns
+			return nil
+		},
+		func(row map[string]interface{}) error {
+			if err := encoder.Encode(row); err != nil {
+				return fmt.Errorf("failed to write job result: %w", err)
+			}
+			j.rows.Add(1)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writer.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write job result: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to write job result: %w", err)
+	}
+	return columns, nil
+}
+
+// cleanup periodically removes jobs whose results have expired
+func (m *Manager) cleanup() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.removeExpired(time.Now())
+		}
+	}
+}
+
+// removeExpired removes finished jobs that are older than the TTL
+func (m *Manager) removeExpired(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, j := range m.jobs {
+		if !j.finishedAt.IsZero() && now.Sub(j.finishedAt) > m.ttl {
+			m.remove(j)
+		}
+	}
+}
+
+// newID returns a random, unguessable job ID
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
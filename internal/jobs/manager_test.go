@@ -0,0 +1,230 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+// stubExecutor returns fixed rows, or blocks until cancelled for queries with SQL "BLOCK"
+type stubExecutor struct {
+	query.QueryExecutor
+}
+
+func (e *stubExecutor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	switch queryConfig.SQL {
+	case "BLOCK":
+		<-ctx.Done()
+		return nil, ctx.Err()
+	case "FAIL":
+		return nil, errors.New("database error")
+	}
+	return []map[string]interface{}{
+		{"id": 1, "name": "Alice, Smith", "tags": []string{"a"}},
+		{"id": 2, "name": nil, "tags": []string{}},
+	}, nil
+}
+
+func (e *stubExecutor) Describe(ctx context.Context, queryConfig config.Query) ([]query.Column, error) {
+	return []query.Column{{Name: "name"}, {Name: "id"}, {Name: "tags"}}, nil
+}
+
+// streamingExecutor streams two rows and holds the second one back until release is closed
+type streamingExecutor struct {
+	query.QueryExecutor
+	release chan struct{}
+}
+
+func (e *streamingExecutor) Stream(ctx context.Context, queryConfig config.Query, params map[string]interface{}, columns func([]string) error, row func(map[string]interface{}) error) (int, error) {
+	if err := columns([]string{"id", "name"}); err != nil {
+		return 0, err
+	}
+	if err := row(map[string]interface{}{"id": 1, "name": "Alice"}); err != nil {
+		return 0, err
+	}
+	select {
+	case <-e.release:
+	case <-ctx.Done():
+		return 1, ctx.Err()
+	}
+	return 2, row(map[string]interface{}{"id": 2, "name": "Bob"})
+}
+
+// waitForState polls a job until it reaches the expected state
+func waitForState(t *testing.T, m *Manager, id string, owner map[string]interface{}, state string) Status {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		status, err := m.Get(id, owner)
+		if err != nil {
+			t.Fatalf("failed to get job: %v", err)
+		}
+		if status.State == state {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected state %s, got %+v", state, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func newTestManager(t *testing.T, jobsConfig config.JobsConfig) *Manager {
+	t.Helper()
+	jobsConfig.Directory = t.TempDir()
+	m, err := NewManager(jobsConfig, &stubExecutor{})
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestManager(t *testing.T) {
+	owner := map[string]interface{}{"tenant_id": "t1"}
+
+	t.Run("CompletedJobResult", func(t *testing.T) {
+		m := newTestManager(t, config.JobsConfig{})
+
+		status, err := m.Submit("users", config.Query{SQL: "SELECT"}, nil, owner)
+		if err != nil {
+			t.Fatalf("failed to submit job: %v", err)
+		}
+		status = waitForState(t, m, status.ID, owner, StateCompleted)
+		if status.Rows != 2 || status.ExpiresAt == nil {
+			t.Errorf("unexpected status: %+v", status)
+		}
+
+		file, columns, err := m.Result(status.ID, owner)
+		if err != nil {
+			t.Fatalf("failed to open result: %v", err)
+		}
+		defer file.Close()
+
+		var csv bytes.Buffer
+		if err := WriteCSV(&csv, file, columns); err != nil {
+			t.Fatalf("failed to write CSV: %v", err)
+		}
+		expected := "name,id,tags\n\"Alice, Smith\",1,\"[\"\"a\"\"]\"\n,2,[]\n"
+		if csv.String() != expected {
+			t.Errorf("expected CSV %q, got %q", expected, csv.String())
+		}
+	})
+
+	t.Run("StreamedJobProgress", func(t *testing.T) {
+		executor := &streamingExecutor{release: make(chan struct{})}
+		m, err := NewManager(config.JobsConfig{Directory: t.TempDir()}, executor)
+		if err != nil {
+			t.Fatalf("failed to create manager: %v", err)
+		}
+		defer m.Close()
+
+		status, _ := m.Submit("report", config.Query{SQL: "SELECT"}, nil, nil)
+		deadline := time.Now().Add(2 * time.Second)
+		for status.Rows != 1 {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected progress of 1 row while running, got %+v", status)
+			}
+			time.Sleep(5 * time.Millisecond)
+			status, _ = m.Get(status.ID, nil)
+		}
+		if status.State != StateRunning {
+			t.Errorf("expected running job, got %+v", status)
+		}
+
+		close(executor.release)
+		status = waitForState(t, m, status.ID, nil, StateCompleted)
+		if status.Rows != 2 {
+			t.Errorf("expected 2 rows, got %+v", status)
+		}
+
+		file, columns, err := m.Result(status.ID, nil)
+		if err != nil {
+			t.Fatalf("failed to open result: %v", err)
+		}
+		defer file.Close()
+
+		var csv bytes.Buffer
+		if err := WriteCSV(&csv, file, columns); err != nil {
+			t.Fatalf("failed to write CSV: %v", err)
+		}
+		if expected := "id,name\n1,Alice\n2,Bob\n"; csv.String() != expected {
+			t.Errorf("expected CSV %q, got %q", expected, csv.String())
+		}
+	})
+
+	t.Run("OtherOwnersCannotAccessJob", func(t *testing.T) {
+		m := newTestManager(t, config.JobsConfig{})
+
+		status, _ := m.Submit("users", config.Query{SQL: "SELECT"}, nil, owner)
+		if _, err := m.Get(status.ID, map[string]interface{}{"tenant_id": "t2"}); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+		if _, err := m.Get(status.ID, nil); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("FailedJob", func(t *testing.T) {
+		m := newTestManager(t, config.JobsConfig{})
+
+		status, _ := m.Submit("users", config.Query{SQL: "FAIL"}, nil, nil)
+		status = waitForState(t, m, status.ID, nil, StateFailed)
+		if status.Error != "database error" {
+			t.Errorf("unexpected error: %q", status.Error)
+		}
+		if _, _, err := m.Result(status.ID, nil); !errors.Is(err, ErrNotCompleted) {
+			t.Errorf("expected ErrNotCompleted, got %v", err)
+		}
+	})
+
+	t.Run("CancelRunningJob", func(t *testing.T) {
+		m := newTestManager(t, config.JobsConfig{Workers: 1})
+
+		status, _ := m.Submit("report", config.Query{SQL: "BLOCK"}, nil, nil)
+		waitForState(t, m, status.ID, nil, StateRunning)
+
+		if _, err := m.Cancel(status.ID, nil); err != nil {
+			t.Fatalf("failed to cancel job: %v", err)
+		}
+		waitForState(t, m, status.ID, nil, StateCancelled)
+	})
+
+	t.Run("QueueFull", func(t *testing.T) {
+		m := newTestManager(t, config.JobsConfig{Workers: 1, MaxQueued: 1})
+
+		running, _ := m.Submit("report", config.Query{SQL: "BLOCK"}, nil, nil)
+		waitForState(t, m, running.ID, nil, StateRunning)
+
+		if _, err := m.Submit("report", config.Query{SQL: "BLOCK"}, nil, nil); err != nil {
+			t.Fatalf("expected second job to be queued, got %v", err)
+		}
+		if _, err := m.Submit("report", config.Query{SQL: "BLOCK"}, nil, nil); !errors.Is(err, ErrQueueFull) {
+			t.Errorf("expected ErrQueueFull, got %v", err)
+		}
+	})
+
+	t.Run("ExpiredJobsAreRemoved", func(t *testing.T) {
+		m := newTestManager(t, config.JobsConfig{TTL: time.Minute})
+
+		status, _ := m.Submit("users", config.Query{SQL: "SELECT"}, nil, nil)
+		waitForState(t, m, status.ID, nil, StateCompleted)
+
+		m.removeExpired(time.Now().Add(2 * time.Minute))
+		if _, err := m.Get(status.ID, nil); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	})
+}
+
+func TestWriteCSV_InvalidInput(t *testing.T) {
+	if err := WriteCSV(io.Discard, bytes.NewBufferString("not json"), []string{"id"}); err == nil {
+		t.Error("expected error for invalid input")
+	}
+}
@@ -9,7 +9,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"math"
 	"math/big"
 	"math/rand"
@@ -17,11 +17,20 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracerName identifies the spans recorded for JWKS refreshes
+const tracerName = "github.com/shogotsuneto/simple-query-server/internal/jwt"
+
 // JWKSCache represents a cached JWKS with TTL
 type JWKSCache struct {
 	fetchedAt time.Time
@@ -56,10 +65,15 @@ type JWKSClient struct {
 	ctx         context.Context
 	cancel      context.CancelFunc
 	refreshDone chan struct{}
-	initialized chan struct{} // signals when initial fetch is complete
+	initialized chan struct{}   // signals when initial fetch is complete
+	refreshNow  chan chan error // forced refreshes, answered with their outcome
 
 	// Exponential backoff for failed refresh attempts
 	failureCount int
+
+	// Refresh outcomes since the client was created
+	refreshSuccesses atomic.Int64
+	refreshFailures  atomic.Int64
 }
 
 // NewJWKSClient creates a new JWKS client with configurable fallback TTL
@@ -80,6 +94,7 @@ func NewJWKSClient(jwksURL string, fallbackTTL time.Duration) *JWKSClient {
 		cancel:       cancel,
 		refreshDone:  make(chan struct{}),
 		initialized:  make(chan struct{}),
+		refreshNow:   make(chan chan error),
 		failureCount: 0,
 	}
 
@@ -129,6 +144,31 @@ func (c *JWKSClient) Close() {
 	<-c.refreshDone
 }
 
+// RefreshCounts returns the number of successful and failed JWKS refreshes
+func (c *JWKSClient) RefreshCounts() (successes int64, failures int64) {
+	return c.refreshSuccesses.Load(), c.refreshFailures.Load()
+}
+
+// Refresh fetches the JWKS immediately, e.g. after the identity provider rotated its keys,
+// and reschedules the background refresh accordingly. It returns the outcome of the fetch.
+func (c *JWKSClient) Refresh(ctx context.Context) error {
+	result := make(chan error, 1)
+	select {
+	case c.refreshNow <- result:
+	case <-c.refreshDone:
+		return fmt.Errorf("JWKS client is closed")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // GetPublicKey retrieves the public key for the given key ID from local cache only
 func (c *JWKSClient) GetPublicKey(kid string) (*rsa.PublicKey, error) {
 	c.cacheMutex.RLock()
@@ -159,6 +199,8 @@ func (c *JWKSClient) backgroundRefresh() {
 			return
 		case <-time.After(waitDuration):
 			c.performRefresh()
+		case result := <-c.refreshNow:
+			result <- c.performRefresh()
 		}
 	}
 }
@@ -208,32 +250,47 @@ func (c *JWKSClient) calculateBackoffDuration() time.Duration {
 }
 
 // performRefresh fetches JWKS and updates cache, handling errors gracefully
-func (c *JWKSClient) performRefresh() {
-	newCache, err := c.fetchJWKSFromServer()
+func (c *JWKSClient) performRefresh() error {
+	ctx, span := otel.Tracer(tracerName).Start(c.ctx, "jwks.refresh",
+		trace.WithAttributes(semconv.URLFull(c.jwksURL)))
+	defer span.End()
+
+	newCache, err := c.fetchJWKSFromServer(ctx)
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
 		// Log error but don't block - this allows server to start without JWKS being available
-		// In production, you might want to use a proper logger
-		log.Printf("JWKS refresh failed: %v", err)
+		slog.ErrorContext(ctx, "JWKS refresh failed", "error", err)
+		c.refreshFailures.Add(1)
 
 		// Increment failure count for exponential backoff
 		c.cacheMutex.Lock()
 		c.failureCount++
 		c.cacheMutex.Unlock()
-		return
+		return err
 	}
 
 	// Success - reset failure count and update cache
+	c.refreshSuccesses.Add(1)
 	c.cacheMutex.Lock()
 	c.failureCount = 0
 	c.cache.fetchedAt = newCache.fetchedAt
 	c.cache.keysByID = newCache.keysByID
 	c.cache.ttl = newCache.ttl
 	c.cacheMutex.Unlock()
+	return nil
 }
 
 // fetchJWKSFromServer fetches JWKS from the server and returns a new cache without updating the existing one
-func (c *JWKSClient) fetchJWKSFromServer() (*JWKSCache, error) {
-	response, err := c.httpClient.Get(c.jwksURL)
+func (c *JWKSClient) fetchJWKSFromServer(ctx context.Context) (*JWKSCache, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.jwksURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(request.Header))
+
+	response, err := c.httpClient.Do(request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch JWKS from %s: %w", c.jwksURL, err)
 	}
@@ -1,8 +1,10 @@
 package jwt
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -106,6 +108,46 @@ func TestJWKSClient_BackgroundRefresh(t *testing.T) {
 	}
 }
 
+func TestJWKSClient_Refresh(t *testing.T) {
+	var requestCount atomic.Int32
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"keys": [{"kty": "RSA", "kid": "test-key-1", "use": "sig", "n": "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISzIWzYr_W6UU9dwuW6TU0DjW0nQcaOLGOjQhGnOGKZ9CW7PDNE2J", "e": "AQAB"}]}`))
+	}))
+	defer server.Close()
+
+	client := NewJWKSClient(server.URL, 10*time.Minute)
+	defer client.Close()
+	client.WaitForInitialization()
+
+	// A forced refresh fetches immediately, long before the scheduled refresh
+	if err := client.Refresh(context.Background()); err != nil {
+		t.Fatalf("Expected refresh to succeed, got %v", err)
+	}
+	if count := requestCount.Load(); count != 2 {
+		t.Fatalf("Expected 2 requests after a forced refresh, got %d", count)
+	}
+
+	failing.Store(true)
+	if err := client.Refresh(context.Background()); err == nil {
+		t.Fatal("Expected refresh to report the failed fetch")
+	}
+	if successes, failures := client.RefreshCounts(); successes != 2 || failures != 1 {
+		t.Errorf("Expected 2 successful and 1 failed refresh, got %d and %d", successes, failures)
+	}
+
+	// Keys of the last successful fetch remain usable
+	if _, err := client.GetPublicKey("test-key-1"); err != nil {
+		t.Errorf("Expected key to remain cached, got %v", err)
+	}
+}
+
 func TestConstructRSAPublicKey(t *testing.T) {
 	// Test with valid modulus and exponent
 	modulus := "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISzIWzYr_W6UU9dwuW6TU0DjW0nQcaOLGOjQhGnOGKZ9CW7PDNE2J"
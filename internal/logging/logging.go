@@ -0,0 +1,169 @@
+// Package logging configures structured logging, tags log lines with request ids and
+// redacts query parameters
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+)
+
+// RequestIDHeader is the header carrying the request id of a request and its response
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength limits the length of request ids accepted from clients
+const maxRequestIDLength = 128
+
+// redacted replaces the values of sensitive parameters in log lines
+const redacted = "[REDACTED]"
+
+// contextKey is a private type for context keys to avoid collisions
+type contextKey string
+
+const requestIDKey contextKey = "request_id"
+
+// logParameters controls whether query parameters are logged at all
+var logParameters atomic.Bool
+
+func init() {
+	logParameters.Store(true)
+}
+
+// Setup installs the default logger writing to w in the configured format and level.
+// Lines logged with a request context carry the request id. Messages of the standard
+// log package are written through the same logger.
+func Setup(w io.Writer, loggingConfig config.LoggingConfig) error {
+	var level slog.Level
+	if loggingConfig.Level != "" {
+		if err := level.UnmarshalText([]byte(loggingConfig.Level)); err != nil {
+			return fmt.Errorf("invalid log level '%s': %w", loggingConfig.Level, err)
+		}
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch loggingConfig.Format {
+	case "", "json":
+		handler = slog.NewJSONHandler(w, options)
+	case "text":
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return fmt.Errorf("unsupported log format '%s' (supported: json, text)", loggingConfig.Format)
+	}
+
+	slog.SetDefault(slog.New(contextHandler{handler}))
+	logParameters.Store(loggingConfig.LogParameters == nil || *loggingConfig.LogParameters)
+	return nil
+}
+
+// contextHandler adds the request id of the logging context to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// WithRequestID returns a copy of the context carrying the request id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request id carried by the context, if any
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// NewRequestID generates a random request id
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ValidRequestID reports whether a request id received from a client can be used as is
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	return !strings.ContainsFunc(id, func(r rune) bool { return r < 0x21 || r > 0x7e })
+}
+
+// Handler assigns every request an id, taken from its X-Request-ID header if valid or
+// generated otherwise, and returns it in the X-Request-ID response header
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !ValidRequestID(id) {
+			id = NewRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), id)))
+	})
+}
+
+// ParametersEnabled reports whether query parameter values may be logged
+func ParametersEnabled() bool {
+	return logParameters.Load()
+}
+
+// Params returns a log attribute with the parameters of a query execution. Values of
+// parameters declared sensitive are redacted, and the attribute is empty when parameter
+// logging is disabled.
+func Params(queryConfig config.Query, params map[string]interface{}) slog.Attr {
+	if !ParametersEnabled() {
+		// Empty attributes are ignored by handlers
+		return slog.Attr{}
+	}
+
+	redactedParams := Redact(queryConfig, params)
+	attrs := make([]any, 0, len(redactedParams))
+	for _, name := range slices.Sorted(maps.Keys(redactedParams)) {
+		attrs = append(attrs, slog.Any(name, redactedParams[name]))
+	}
+	return slog.Group("params", attrs...)
+}
+
+// Redact returns a copy of the parameters of a query execution in which the values of
+// parameters declared sensitive are replaced, regardless of whether parameters are logged
+func Redact(queryConfig config.Query, params map[string]interface{}) map[string]interface{} {
+	sensitive := make(map[string]bool)
+	for _, param := range slices.Concat(queryConfig.Params, queryConfig.MiddlewareParams) {
+		if param.Sensitive {
+			sensitive[param.Name] = true
+		}
+	}
+
+	redactedParams := make(map[string]interface{}, len(params))
+	for name, value := range params {
+		if sensitive[name] {
+			redactedParams[name] = redacted
+		} else {
+			redactedParams[name] = value
+		}
+	}
+	return redactedParams
+}
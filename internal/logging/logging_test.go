@@ -0,0 +1,173 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+)
+
+// setup installs a JSON logger writing to a buffer and restores the previous one after the test
+func setup(t *testing.T, loggingConfig config.LoggingConfig) *bytes.Buffer {
+	t.Helper()
+	previous := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		logParameters.Store(true)
+	})
+
+	var buf bytes.Buffer
+	if err := Setup(&buf, loggingConfig); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	return &buf
+}
+
+// decode parses the single JSON log line in the buffer
+func decode(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("failed to parse log line %q: %v", buf.String(), err)
+	}
+	return line
+}
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name          string
+		loggingConfig config.LoggingConfig
+		expectError   bool
+	}{
+		{name: "defaults", loggingConfig: config.LoggingConfig{}},
+		{name: "text format", loggingConfig: config.LoggingConfig{Level: "debug", Format: "text"}},
+		{name: "invalid level", loggingConfig: config.LoggingConfig{Level: "verbose"}, expectError: true},
+		{name: "invalid format", loggingConfig: config.LoggingConfig{Format: "xml"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := slog.Default()
+			defer slog.SetDefault(previous)
+
+			err := Setup(&bytes.Buffer{}, tt.loggingConfig)
+			if tt.expectError && err == nil {
+				t.Error("expected an error")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSetup_Level(t *testing.T) {
+	buf := setup(t, config.LoggingConfig{Level: "warn"})
+
+	slog.Info("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("expected info lines to be dropped, got %q", buf.String())
+	}
+	slog.Warn("shown")
+	if line := decode(t, buf); line["msg"] != "shown" || line["level"] != "WARN" {
+		t.Errorf("unexpected log line: %v", line)
+	}
+}
+
+func TestRequestIDInLogLines(t *testing.T) {
+	buf := setup(t, config.LoggingConfig{})
+
+	slog.InfoContext(WithRequestID(context.Background(), "abc123"), "handled", "query", "users")
+	line := decode(t, buf)
+	if line["request_id"] != "abc123" {
+		t.Errorf("expected request_id abc123, got %v", line["request_id"])
+	}
+	if line["query"] != "users" {
+		t.Errorf("expected query users, got %v", line["query"])
+	}
+
+	buf.Reset()
+	slog.Info("no request")
+	if _, ok := decode(t, buf)["request_id"]; ok {
+		t.Error("expected no request_id without a request context")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expectID string
+	}{
+		{name: "client id", header: "client-id-1", expectID: "client-id-1"},
+		{name: "no id"},
+		{name: "invalid id", header: "has space"},
+		{name: "too long", header: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var contextID string
+			handler := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contextID = RequestID(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set(RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			responseID := w.Header().Get(RequestIDHeader)
+			if responseID != contextID {
+				t.Errorf("response id %q does not match context id %q", responseID, contextID)
+			}
+			if tt.expectID != "" && responseID != tt.expectID {
+				t.Errorf("expected id %q, got %q", tt.expectID, responseID)
+			}
+			if tt.expectID == "" && (responseID == tt.header || len(responseID) != 32) {
+				t.Errorf("expected a generated id, got %q", responseID)
+			}
+		})
+	}
+}
+
+func TestParams(t *testing.T) {
+	queryConfig := config.Query{
+		Params: []config.QueryParam{
+			{Name: "email", Type: "string"},
+			{Name: "password", Type: "string", Sensitive: true},
+		},
+		MiddlewareParams: []config.QueryParam{
+			{Name: "token", Type: "string", Sensitive: true},
+		},
+	}
+	params := map[string]interface{}{"email": "a@example.com", "password": "secret", "token": "t0k3n"}
+
+	buf := setup(t, config.LoggingConfig{})
+	slog.Info("executing", Params(queryConfig, params))
+	logged, _ := decode(t, buf)["params"].(map[string]interface{})
+	expected := map[string]interface{}{"email": "a@example.com", "password": redacted, "token": redacted}
+	for name, value := range expected {
+		if logged[name] != value {
+			t.Errorf("expected param %s to be logged as %v, got %v", name, value, logged[name])
+		}
+	}
+	if strings.Contains(buf.String(), "secret") || strings.Contains(buf.String(), "t0k3n") {
+		t.Errorf("sensitive values leaked into the log: %s", buf.String())
+	}
+
+	disabled := false
+	buf = setup(t, config.LoggingConfig{LogParameters: &disabled})
+	slog.Info("executing", Params(queryConfig, params))
+	if _, ok := decode(t, buf)["params"]; ok {
+		t.Errorf("expected no params with parameter logging disabled, got %s", buf.String())
+	}
+}
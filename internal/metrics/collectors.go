@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shogotsuneto/simple-query-server/internal/cache"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+)
+
+// newDesc creates the description of a metric collected from component state
+func newDesc(name string, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, labels, nil)
+}
+
+var (
+	dbUpDesc                = newDesc("db_up", "Whether the database connection is healthy (1) or not (0).")
+	dbReconnectsDesc        = newDesc("db_reconnects_total", "Number of times the database connection was re-established.")
+	dbMaxOpenDesc           = newDesc("db_max_open_connections", "Maximum number of open connections to the database.")
+	dbOpenDesc              = newDesc("db_open_connections", "Number of established connections, both in use and idle.")
+	dbInUseDesc             = newDesc("db_in_use_connections", "Number of connections currently in use.")
+	dbIdleDesc              = newDesc("db_idle_connections", "Number of idle connections.")
+	dbWaitCountDesc         = newDesc("db_wait_count_total", "Number of connections waited for.")
+	dbWaitDurationDesc      = newDesc("db_wait_duration_seconds_total", "Total time blocked waiting for a new connection.")
+	dbMaxIdleClosedDesc     = newDesc("db_max_idle_closed_total", "Number of connections closed due to the maximum number of idle connections.")
+	dbMaxIdleTimeClosedDesc = newDesc("db_max_idle_time_closed_total", "Number of connections closed due to the maximum idle time.")
+	dbMaxLifetimeClosedDesc = newDesc("db_max_lifetime_closed_total", "Number of connections closed due to the maximum connection lifetime.")
+
+	middlewareRejectionsDesc = newDesc("middleware_rejections_total", "Number of requests refused by a middleware.", "middleware")
+	jwksRefreshesDesc        = newDesc("jwks_refreshes_total", "Number of JWKS refreshes by outcome (success or failure).", "middleware", "result")
+
+	cacheEntriesDesc       = newDesc("cache_entries", "Number of cached results of a query.", "query")
+	cacheHitsDesc          = newDesc("cache_hits_total", "Number of executions served from the result cache.", "query")
+	cacheMissesDesc        = newDesc("cache_misses_total", "Number of executions not found in the result cache.", "query")
+	cacheEvictionsDesc     = newDesc("cache_evictions_total", "Number of cached results evicted to make room for new ones.", "query")
+	cacheInvalidationsDesc = newDesc("cache_invalidations_total", "Number of times the cached results of a query were invalidated.", "query")
+)
+
+// databaseCollector collects the state of a database connection
+type databaseCollector struct {
+	database Database
+}
+
+func (c *databaseCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		dbUpDesc, dbReconnectsDesc, dbMaxOpenDesc, dbOpenDesc, dbInUseDesc, dbIdleDesc,
+		dbWaitCountDesc, dbWaitDurationDesc, dbMaxIdleClosedDesc, dbMaxIdleTimeClosedDesc, dbMaxLifetimeClosedDesc,
+	} {
+		ch <- desc
+	}
+}
+
+func (c *databaseCollector) Collect(ch chan<- prometheus.Metric) {
+	up := 0.0
+	if c.database.IsHealthy() {
+		up = 1
+	}
+	stats := c.database.DBStats()
+
+	ch <- prometheus.MustNewConstMetric(dbUpDesc, prometheus.GaugeValue, up)
+	ch <- prometheus.MustNewConstMetric(dbReconnectsDesc, prometheus.CounterValue, float64(c.database.Reconnects()))
+	ch <- prometheus.MustNewConstMetric(dbMaxOpenDesc, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(dbOpenDesc, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(dbInUseDesc, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(dbIdleDesc, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(dbWaitCountDesc, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(dbWaitDurationDesc, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(dbMaxIdleClosedDesc, prometheus.CounterValue, float64(stats.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(dbMaxIdleTimeClosedDesc, prometheus.CounterValue, float64(stats.MaxIdleTimeClosed))
+	ch <- prometheus.MustNewConstMetric(dbMaxLifetimeClosedDesc, prometheus.CounterValue, float64(stats.MaxLifetimeClosed))
+}
+
+// middlewareCollector collects the rejections and JWKS refreshes of a middleware chain
+type middlewareCollector struct {
+	chain atomic.Pointer[middleware.Chain]
+}
+
+func (c *middlewareCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- middlewareRejectionsDesc
+	ch <- jwksRefreshesDesc
+}
+
+// Collect sums the counters of middlewares sharing a name, such as two bearer-jwks
+// entries for the same JWKS URL, as a metric may only be collected once per label set
+func (c *middlewareCollector) Collect(ch chan<- prometheus.Metric) {
+	rejections := make(map[string]int64)
+	refreshes := make(map[string][2]int64)
+	for _, mw := range *c.chain.Load() {
+		name := mw.Name()
+		if counter, ok := mw.(middleware.RejectionCounter); ok {
+			rejections[name] += counter.Rejections()
+		}
+		if refresher, ok := mw.(JWKSRefresher); ok {
+			successes, failures := refresher.JWKSRefreshCounts()
+			counts := refreshes[name]
+			refreshes[name] = [2]int64{counts[0] + successes, counts[1] + failures}
+		}
+	}
+
+	for name, count := range rejections {
+		ch <- prometheus.MustNewConstMetric(middlewareRejectionsDesc, prometheus.CounterValue, float64(count), name)
+	}
+	for name, counts := range refreshes {
+		ch <- prometheus.MustNewConstMetric(jwksRefreshesDesc, prometheus.CounterValue, float64(counts[0]), name, "success")
+		ch <- prometheus.MustNewConstMetric(jwksRefreshesDesc, prometheus.CounterValue, float64(counts[1]), name, "failure")
+	}
+}
+
+// cacheCollector collects the result cache statistics of every cached query
+type cacheCollector struct {
+	cache *cache.Executor
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheEntriesDesc
+	ch <- cacheHitsDesc
+	ch <- cacheMissesDesc
+	ch <- cacheEvictionsDesc
+	ch <- cacheInvalidationsDesc
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, stats := range c.cache.Stats() {
+		ch <- prometheus.MustNewConstMetric(cacheEntriesDesc, prometheus.GaugeValue, float64(stats.Entries), name)
+		ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(stats.Hits), name)
+		ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(stats.Misses), name)
+		ch <- prometheus.MustNewConstMetric(cacheEvictionsDesc, prometheus.CounterValue, float64(stats.Evictions), name)
+		ch <- prometheus.MustNewConstMetric(cacheInvalidationsDesc, prometheus.CounterValue, float64(stats.Invalidations), name)
+	}
+}
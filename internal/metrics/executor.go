@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+// Executor records the count, latency, returned rows and errors of every query execution,
+// including executions inside snapshots. It wraps another QueryExecutor; all other methods
+// are passed through.
+type Executor struct {
+	query.QueryExecutor
+
+	metrics *Metrics
+}
+
+// NewExecutor wraps an executor with query execution metrics
+func (m *Metrics) NewExecutor(next query.QueryExecutor) *Executor {
+	return &Executor{QueryExecutor: next, metrics: m}
+}
+
+// Execute runs the query and records its execution
+func (e *Executor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	start := time.Now()
+	rows, err := e.QueryExecutor.Execute(ctx, queryConfig, params)
+	e.metrics.observe(queryConfig.Name, time.Since(start), len(rows), err)
+	return rows, err
+}
+
+// Stream runs the query, passing its rows on as they are read, and records its execution
+func (e *Executor) Stream(ctx context.Context, queryConfig config.Query, params map[string]interface{}, columns func([]string) error, row func(map[string]interface{}) error) (int, error) {
+	start := time.Now()
+	count, err := query.Stream(ctx, e.QueryExecutor, queryConfig, params, columns, row)
+	e.metrics.observe(queryConfig.Name, time.Since(start), count, err)
+	return count, err
+}
+
+// BeginSnapshot starts a snapshot whose executions are recorded
+func (e *Executor) BeginSnapshot(ctx context.Context) (query.Snapshot, error) {
+	snapshot, err := e.QueryExecutor.BeginSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshotExecutor{Snapshot: snapshot, metrics: e.metrics}, nil
+}
+
+// snapshotExecutor records the executions of a snapshot
+type snapshotExecutor struct {
+	query.Snapshot
+
+	metrics *Metrics
+}
+
+// Execute runs the query inside the snapshot and records its execution
+func (s *snapshotExecutor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	start := time.Now()
+	rows, err := s.Snapshot.Execute(ctx, queryConfig, params)
+	s.metrics.observe(queryConfig.Name, time.Since(start), len(rows), err)
+	return rows, err
+}
@@ -0,0 +1,120 @@
+// Package metrics exposes the server's telemetry in the Prometheus text format
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/shogotsuneto/simple-query-server/internal/cache"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+const namespace = "query_server"
+
+// Database is implemented by executors that report the state of their database connection
+type Database interface {
+	IsHealthy() bool
+	DBStats() sql.DBStats
+	Reconnects() int64
+}
+
+// JWKSRefresher is implemented by middleware that refreshes a JWKS in the background
+type JWKSRefresher interface {
+	JWKSRefreshCounts() (successes int64, failures int64)
+}
+
+// Metrics records query executions and collects the state of the server's components
+type Metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	rows     *prometheus.CounterVec
+
+	middleware *middlewareCollector // nil until middleware is registered
+}
+
+// New creates metrics with the query execution metrics and the Go runtime and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "query_requests_total",
+			Help:      "Number of query executions.",
+		}, []string{"query"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "query_errors_total",
+			Help:      "Number of failed query executions by error class (client or server).",
+		}, []string{"query", "class"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "query_duration_seconds",
+			Help:      "Query execution latency.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"query"}),
+		rows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "query_rows_returned_total",
+			Help:      "Number of rows returned by query executions.",
+		}, []string{"query"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests, m.errors, m.duration, m.rows,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// observe records one query execution
+func (m *Metrics) observe(name string, duration time.Duration, rows int, err error) {
+	m.requests.WithLabelValues(name).Inc()
+	m.duration.WithLabelValues(name).Observe(duration.Seconds())
+	if err != nil {
+		class := "server"
+		if query.IsClientError(err) {
+			class = "client"
+		}
+		m.errors.WithLabelValues(name, class).Inc()
+		return
+	}
+	m.rows.WithLabelValues(name).Add(float64(rows))
+}
+
+// RegisterDatabase collects the connection pool statistics, health and reconnections of a database
+func (m *Metrics) RegisterDatabase(database Database) {
+	m.registry.MustRegister(&databaseCollector{database: database})
+}
+
+// RegisterMiddleware collects the rejections of every middleware in the chain counting them,
+// and the JWKS refreshes of middleware verifying tokens
+func (m *Metrics) RegisterMiddleware(chain middleware.Chain) {
+	m.middleware = &middlewareCollector{}
+	m.middleware.chain.Store(&chain)
+	m.registry.MustRegister(m.middleware)
+}
+
+// UpdateMiddleware collects the given chain instead of the registered one, e.g. after a
+// configuration reload. The counters of new middleware start from zero.
+func (m *Metrics) UpdateMiddleware(chain middleware.Chain) {
+	if m.middleware != nil {
+		m.middleware.chain.Store(&chain)
+	}
+}
+
+// RegisterResultCache collects the statistics of the result cache
+func (m *Metrics) RegisterResultCache(resultCache *cache.Executor) {
+	m.registry.MustRegister(&cacheCollector{cache: resultCache})
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+// rowsExecutor returns one row per "rows" parameter, or an error chosen by the query SQL
+type rowsExecutor struct {
+	query.QueryExecutor
+}
+
+func (e *rowsExecutor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	switch queryConfig.SQL {
+	case "CLIENT_ERROR":
+		return nil, query.NewClientError("required parameter 'id' is missing")
+	case "SERVER_ERROR":
+		return nil, errors.New("database error")
+	}
+	rows := make([]map[string]interface{}, params["rows"].(int))
+	for i := range rows {
+		rows[i] = map[string]interface{}{"id": i}
+	}
+	return rows, nil
+}
+
+// fakeDatabase reports fixed connection state
+type fakeDatabase struct{}
+
+func (fakeDatabase) IsHealthy() bool      { return true }
+func (fakeDatabase) DBStats() sql.DBStats { return sql.DBStats{OpenConnections: 3, InUse: 1, Idle: 2} }
+func (fakeDatabase) Reconnects() int64    { return 2 }
+
+// fakeJWKSMiddleware reports fixed JWKS refresh counts
+type fakeJWKSMiddleware struct{}
+
+func (fakeJWKSMiddleware) Name() string                                { return "bearer-jwks(test)" }
+func (fakeJWKSMiddleware) JWKSRefreshCounts() (int64, int64)           { return 5, 1 }
+func (fakeJWKSMiddleware) Wrap(next http.HandlerFunc) http.HandlerFunc { return next }
+
+// scrape returns the metrics served by the handler
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, body)
+	}
+	return string(body)
+}
+
+func TestMetrics(t *testing.T) {
+	m := New()
+	executor := m.NewExecutor(&rowsExecutor{})
+
+	executor.Execute(context.Background(), config.Query{Name: "users", SQL: "SELECT"}, map[string]interface{}{"rows": 3})
+	executor.Execute(context.Background(), config.Query{Name: "users", SQL: "SELECT"}, map[string]interface{}{"rows": 2})
+	executor.Execute(context.Background(), config.Query{Name: "users", SQL: "CLIENT_ERROR"}, nil)
+	executor.Execute(context.Background(), config.Query{Name: "orders", SQL: "SERVER_ERROR"}, nil)
+
+	headerMiddleware := middleware.NewHTTPHeaderMiddleware(middleware.HTTPHeaderConfig{Header: "X-User-ID", Parameter: "user_id", Required: true})
+	rejected := httptest.NewRecorder()
+	headerMiddleware.Wrap(func(w http.ResponseWriter, r *http.Request) {})(rejected, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	m.RegisterDatabase(fakeDatabase{})
+	// Middlewares sharing a name are reported together
+	m.RegisterMiddleware(middleware.Chain{headerMiddleware, fakeJWKSMiddleware{}, fakeJWKSMiddleware{}})
+
+	output := scrape(t, m)
+	for _, expected := range []string{
+		`query_server_query_requests_total{query="users"} 3`,
+		`query_server_query_requests_total{query="orders"} 1`,
+		`query_server_query_rows_returned_total{query="users"} 5`,
+		`query_server_query_errors_total{class="client",query="users"} 1`,
+		`query_server_query_errors_total{class="server",query="orders"} 1`,
+		`query_server_query_duration_seconds_count{query="users"} 3`,
+		`query_server_db_up 1`,
+		`query_server_db_reconnects_total 2`,
+		`query_server_db_open_connections 3`,
+		`query_server_db_in_use_connections 1`,
+		`query_server_middleware_rejections_total{middleware="http-header(X-User-ID->user_id)"} 1`,
+		`query_server_jwks_refreshes_total{middleware="bearer-jwks(test)",result="success"} 10`,
+		`query_server_jwks_refreshes_total{middleware="bearer-jwks(test)",result="failure"} 2`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected metrics to contain %q", expected)
+		}
+	}
+}
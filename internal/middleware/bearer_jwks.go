@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -22,6 +23,7 @@ type BearerJWKSConfig struct {
 
 // BearerJWKSMiddleware verifies JWT tokens using JWKS and injects claims as SQL parameters
 type BearerJWKSMiddleware struct {
+	rejectionCount
 	config            BearerJWKSConfig
 	jwksClient        *jwt.JWKSClient
 	enableHealthCheck bool // resolved value for health check (defaults to true)
@@ -52,70 +54,61 @@ func NewBearerJWKSMiddleware(config BearerJWKSConfig) *BearerJWKSMiddleware {
 
 // Wrap wraps an http.HandlerFunc with this middleware
 func (m *BearerJWKSMiddleware) Wrap(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Extract Authorization header
-		authHeader := r.Header.Get("Authorization")
-
-		if authHeader == "" {
-			if m.config.Required {
-				http.Error(w, "Authorization header is required", http.StatusUnauthorized)
-				return
-			}
-			// If not required and missing, continue without authentication
-			next.ServeHTTP(w, r)
-			return
-		}
+	return wrapExtractor(m, next)
+}
 
-		// Check for Bearer token format
-		if !strings.HasPrefix(authHeader, "Bearer ") {
-			if m.config.Required {
-				http.Error(w, "Authorization header must be a Bearer token", http.StatusUnauthorized)
-				return
-			}
-			// If not required and malformed, continue without authentication
-			next.ServeHTTP(w, r)
-			return
-		}
+// ExtractParams verifies the bearer token in the Authorization header and maps its claims to parameters
+func (m *BearerJWKSMiddleware) ExtractParams(header http.Header) (map[string]interface{}, error) {
+	// Extract Authorization header
+	authHeader := header.Get("Authorization")
 
-		// Extract token from "Bearer <token>"
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		tokenString = strings.TrimSpace(tokenString)
-
-		if tokenString == "" {
-			if m.config.Required {
-				http.Error(w, "Bearer token is empty", http.StatusUnauthorized)
-				return
-			}
-			next.ServeHTTP(w, r)
-			return
+	if authHeader == "" {
+		if m.config.Required {
+			return nil, &Rejection{StatusCode: http.StatusUnauthorized, Message: "Authorization header is required"}
 		}
+		// If not required and missing, continue without authentication
+		return nil, nil
+	}
 
-		// Parse and validate the JWT token
-		claims, err := m.jwksClient.ValidateToken(tokenString, m.config.Issuer, m.config.Audience)
-		if err != nil {
-			if m.config.Required {
-				http.Error(w, fmt.Sprintf("Invalid token: %v", err), http.StatusUnauthorized)
-				return
-			}
-			// If not required and invalid, continue without authentication
-			next.ServeHTTP(w, r)
-			return
+	// Check for Bearer token format
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		if m.config.Required {
+			return nil, &Rejection{StatusCode: http.StatusUnauthorized, Message: "Authorization header must be a Bearer token"}
 		}
+		// If not required and malformed, continue without authentication
+		return nil, nil
+	}
 
-		// Get existing middleware parameters from context
-		params := GetMiddlewareParams(r)
+	// Extract token from "Bearer <token>"
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	tokenString = strings.TrimSpace(tokenString)
 
-		// Map JWT claims to SQL parameters according to configuration
-		for jwtClaim, sqlParam := range m.config.ClaimsMapping {
-			if claimValue, exists := claims[jwtClaim]; exists {
-				params[sqlParam] = claimValue
-			}
+	if tokenString == "" {
+		if m.config.Required {
+			return nil, &Rejection{StatusCode: http.StatusUnauthorized, Message: "Bearer token is empty"}
 		}
+		return nil, nil
+	}
 
-		// Set updated parameters in context and continue
-		r = SetMiddlewareParams(r, params)
-		next.ServeHTTP(w, r)
+	// Parse and validate the JWT token
+	claims, err := m.jwksClient.ValidateToken(tokenString, m.config.Issuer, m.config.Audience)
+	if err != nil {
+		if m.config.Required {
+			return nil, &Rejection{StatusCode: http.StatusUnauthorized, Message: fmt.Sprintf("Invalid token: %v", err)}
+		}
+		// If not required and invalid, continue without authentication
+		return nil, nil
 	}
+
+	// Map JWT claims to SQL parameters according to configuration
+	params := make(map[string]interface{})
+	for jwtClaim, sqlParam := range m.config.ClaimsMapping {
+		if claimValue, exists := claims[jwtClaim]; exists {
+			params[sqlParam] = claimValue
+		}
+	}
+
+	return params, nil
 }
 
 // Name returns the name of this middleware
@@ -123,6 +116,15 @@ func (m *BearerJWKSMiddleware) Name() string {
 	return fmt.Sprintf("bearer-jwks(%s)", m.config.JWKSURL)
 }
 
+// ProducedParams returns the parameters the claims are mapped to
+func (m *BearerJWKSMiddleware) ProducedParams() []string {
+	params := make([]string, 0, len(m.config.ClaimsMapping))
+	for _, sqlParam := range m.config.ClaimsMapping {
+		params = append(params, sqlParam)
+	}
+	return params
+}
+
 // Close cleans up resources used by the middleware
 func (m *BearerJWKSMiddleware) Close() error {
 	if m.jwksClient != nil {
@@ -139,6 +141,22 @@ func (m *BearerJWKSMiddleware) IsHealthy() bool {
 	return m.jwksClient.IsHealthy()
 }
 
+// JWKSRefreshCounts returns the number of successful and failed JWKS refreshes
+func (m *BearerJWKSMiddleware) JWKSRefreshCounts() (successes int64, failures int64) {
+	if m.jwksClient == nil {
+		return 0, 0
+	}
+	return m.jwksClient.RefreshCounts()
+}
+
+// RefreshJWKS fetches the JWKS immediately instead of waiting for the background refresh
+func (m *BearerJWKSMiddleware) RefreshJWKS(ctx context.Context) error {
+	if m.jwksClient == nil {
+		return fmt.Errorf("JWKS client is not initialized")
+	}
+	return m.jwksClient.Refresh(ctx)
+}
+
 // HealthCheckEnabled returns true if health checking is enabled for this middleware
 func (m *BearerJWKSMiddleware) HealthCheckEnabled() bool {
 	return m.enableHealthCheck
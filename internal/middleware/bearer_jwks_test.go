@@ -199,6 +199,16 @@ func TestCreateBearerJWKSMiddleware_MissingFields(t *testing.T) {
 				"claims_mapping": map[string]interface{}{},
 			},
 		},
+		{
+			name: "unknown field",
+			configMap: map[string]interface{}{
+				"jwks_url": "http://localhost:3000/.well-known/jwks.json",
+				"claims_mapping": map[string]interface{}{
+					"sub": "user_id",
+				},
+				"issuers": "http://localhost:3000",
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 
 	"github.com/shogotsuneto/simple-query-server/internal/config"
 	"gopkg.in/yaml.v3"
@@ -21,14 +23,8 @@ func CreateMiddleware(middlewareConfig config.MiddlewareConfig) (Middleware, err
 
 // createHTTPHeaderMiddleware creates an HTTP header middleware from config
 func createHTTPHeaderMiddleware(configMap map[string]interface{}) (Middleware, error) {
-	// Convert the config map to YAML and back to get proper type conversion
-	yamlData, err := yaml.Marshal(configMap)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal http-header config: %w", err)
-	}
-
 	var httpHeaderConfig HTTPHeaderConfig
-	if err := yaml.Unmarshal(yamlData, &httpHeaderConfig); err != nil {
+	if err := decodeConfig(configMap, &httpHeaderConfig); err != nil {
 		return nil, fmt.Errorf("failed to parse http-header config: %w", err)
 	}
 
@@ -45,14 +41,8 @@ func createHTTPHeaderMiddleware(configMap map[string]interface{}) (Middleware, e
 
 // createBearerJWKSMiddleware creates a bearer JWKS middleware from config
 func createBearerJWKSMiddleware(configMap map[string]interface{}) (Middleware, error) {
-	// Convert the config map to YAML and back to get proper type conversion
-	yamlData, err := yaml.Marshal(configMap)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal bearer-jwks config: %w", err)
-	}
-
 	var jwksConfig BearerJWKSConfig
-	if err := yaml.Unmarshal(yamlData, &jwksConfig); err != nil {
+	if err := decodeConfig(configMap, &jwksConfig); err != nil {
 		return nil, fmt.Errorf("failed to parse bearer-jwks config: %w", err)
 	}
 
@@ -67,6 +57,23 @@ func createBearerJWKSMiddleware(configMap map[string]interface{}) (Middleware, e
 	return NewBearerJWKSMiddleware(jwksConfig), nil
 }
 
+// decodeConfig converts a middleware config map into its typed configuration, rejecting
+// keys the configuration has no field for
+func decodeConfig(configMap map[string]interface{}, out interface{}) error {
+	// Convert the config map to YAML and back to get proper type conversion
+	yamlData, err := yaml.Marshal(configMap)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(yamlData))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
 // CreateMiddlewareChain creates a middleware chain from server configuration
 func CreateMiddlewareChain(serverConfig *config.ServerConfig) (Chain, error) {
 	if serverConfig == nil || len(serverConfig.Middleware) == 0 {
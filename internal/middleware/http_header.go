@@ -14,6 +14,7 @@ type HTTPHeaderConfig struct {
 
 // HTTPHeaderMiddleware extracts values from HTTP headers and makes them available as SQL parameters
 type HTTPHeaderMiddleware struct {
+	rejectionCount
 	config HTTPHeaderConfig
 }
 
@@ -26,32 +27,31 @@ func NewHTTPHeaderMiddleware(config HTTPHeaderConfig) *HTTPHeaderMiddleware {
 
 // Wrap wraps an http.HandlerFunc with this middleware
 func (m *HTTPHeaderMiddleware) Wrap(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		headerValue := r.Header.Get(m.config.Header)
-
-		if headerValue == "" {
-			if m.config.Required {
-				http.Error(w, fmt.Sprintf("required header '%s' is missing", m.config.Header), http.StatusBadRequest)
-				return
-			}
-			// If not required and missing, just continue without adding the parameter
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		// Get existing middleware parameters from context
-		params := GetMiddlewareParams(r)
+	return wrapExtractor(m, next)
+}
 
-		// Add the header value as a parameter
-		params[m.config.Parameter] = headerValue
+// ExtractParams extracts the header value as a parameter
+func (m *HTTPHeaderMiddleware) ExtractParams(header http.Header) (map[string]interface{}, error) {
+	headerValue := header.Get(m.config.Header)
 
-		// Set updated parameters in context and continue
-		r = SetMiddlewareParams(r, params)
-		next.ServeHTTP(w, r)
+	if headerValue == "" {
+		if m.config.Required {
+			return nil, &Rejection{StatusCode: http.StatusBadRequest, Message: fmt.Sprintf("required header '%s' is missing", m.config.Header)}
+		}
+		// If not required and missing, just continue without adding the parameter
+		return nil, nil
 	}
+
+	// Add the header value as a parameter
+	return map[string]interface{}{m.config.Parameter: headerValue}, nil
 }
 
 // Name returns the name of this middleware
 func (m *HTTPHeaderMiddleware) Name() string {
 	return fmt.Sprintf("http-header(%s->%s)", m.config.Header, m.config.Parameter)
 }
+
+// ProducedParams returns the parameter the header value is set as
+func (m *HTTPHeaderMiddleware) ProducedParams() []string {
+	return []string{m.config.Parameter}
+}
@@ -2,13 +2,21 @@ package middleware
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // contextKey is a private type for context keys to avoid collisions
 type contextKey string
 
+// tracerName identifies the spans recorded for middleware
+const tracerName = "github.com/shogotsuneto/simple-query-server/internal/middleware"
+
 const (
 	// MiddlewareParamsKey is the context key for middleware parameters
 	MiddlewareParamsKey contextKey = "middleware_params"
@@ -38,26 +46,171 @@ type HealthChecker interface {
 	HealthCheckEnabled() bool
 }
 
+// ParamExtractor represents a middleware that derives its parameters from request headers
+// independently of the transport, so it can also be applied to e.g. gRPC metadata
+type ParamExtractor interface {
+	Middleware
+	// ExtractParams returns the parameters to inject for the given headers.
+	// A *Rejection error means the request must be refused.
+	ExtractParams(header http.Header) (map[string]interface{}, error)
+}
+
+// ParamProducer represents a middleware that declares the parameters it may inject
+type ParamProducer interface {
+	// ProducedParams returns the names of the parameters the middleware may inject
+	ProducedParams() []string
+}
+
+// RejectionCounter represents a middleware that counts the requests it refused
+type RejectionCounter interface {
+	// Rejections returns the number of requests refused so far
+	Rejections() int64
+}
+
+// rejectionCount implements RejectionCounter for embedding in middleware
+type rejectionCount struct {
+	count atomic.Int64
+}
+
+// Rejections returns the number of requests refused so far
+func (c *rejectionCount) Rejections() int64 {
+	return c.count.Load()
+}
+
+func (c *rejectionCount) reject() {
+	c.count.Add(1)
+}
+
+// countRejection records a refused request for middleware that counts rejections
+func countRejection(middleware Middleware) {
+	if counter, ok := middleware.(interface{ reject() }); ok {
+		counter.reject()
+	}
+}
+
+// Rejection is returned by a ParamExtractor when a request must be refused
+type Rejection struct {
+	StatusCode int    // HTTP status code to respond with
+	Message    string // Message explaining why the request was refused
+}
+
+func (e *Rejection) Error() string {
+	return e.Message
+}
+
+// wrapExtractor wraps an http.HandlerFunc with a ParamExtractor, merging the extracted
+// parameters into the request context or responding with the rejection
+func wrapExtractor(extractor ParamExtractor, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		extracted, err := extractor.ExtractParams(r.Header)
+		if err != nil {
+			countRejection(extractor)
+			statusCode := http.StatusInternalServerError
+			if rejection, ok := err.(*Rejection); ok {
+				statusCode = rejection.StatusCode
+			}
+			http.Error(w, err.Error(), statusCode)
+			return
+		}
+
+		if len(extracted) > 0 {
+			// Get existing middleware parameters from context and add the extracted ones
+			params := GetMiddlewareParams(r)
+			for k, v := range extracted {
+				params[k] = v
+			}
+
+			// Set updated parameters in context
+			r = SetMiddlewareParams(r, params)
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
 // Chain represents a chain of middleware to be executed
 type Chain []Middleware
 
-// Wrap wraps an http.HandlerFunc with all middleware in the chain
+// Wrap wraps an http.HandlerFunc with all middleware in the chain.
+// Each middleware records a span covering its own work.
 func (c Chain) Wrap(handler http.HandlerFunc) http.HandlerFunc {
 	// Wrap middleware in reverse order so the first middleware in the slice
 	// is the outermost middleware (executes first)
 	for i := len(c) - 1; i >= 0; i-- {
-		handler = c[i].Wrap(handler)
+		handler = traceMiddleware(c[i], handler)
 	}
 	return handler
 }
 
+// middlewareSpanKey is the context key for the span of the middleware currently running
+const middlewareSpanKey contextKey = "middleware_span"
+
+// middlewareSpan is the span of a running middleware and the span it is a child of
+type middlewareSpan struct {
+	span   trace.Span
+	parent trace.Span
+	ended  bool
+}
+
+// traceMiddleware wraps a middleware so that it runs in its own span. The span ends when
+// the middleware passes the request on, and the rest of the chain continues under the parent
+// span; a span that ends without passing the request on records the rejection.
+func traceMiddleware(middleware Middleware, next http.HandlerFunc) http.HandlerFunc {
+	tracer := otel.Tracer(tracerName)
+	spanName := "middleware " + middleware.Name()
+
+	wrapped := middleware.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		if current, ok := r.Context().Value(middlewareSpanKey).(*middlewareSpan); ok && !current.ended {
+			current.ended = true
+			current.span.End()
+			r = r.WithContext(trace.ContextWithSpan(r.Context(), current.parent))
+		}
+		next(w, r)
+	})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		parent := trace.SpanFromContext(r.Context())
+		ctx, span := tracer.Start(r.Context(), spanName)
+		current := &middlewareSpan{span: span, parent: parent}
+
+		wrapped(w, r.WithContext(context.WithValue(ctx, middlewareSpanKey, current)))
+
+		if !current.ended {
+			span.SetStatus(codes.Error, "request refused")
+			span.End()
+		}
+	}
+}
+
+// ExtractParams runs every ParamExtractor of the chain in order against the given headers
+// and returns the merged parameters. Middleware that cannot extract parameters from
+// headers alone is skipped.
+func (c Chain) ExtractParams(header http.Header) (map[string]interface{}, error) {
+	params := make(map[string]interface{})
+	for _, middleware := range c {
+		extractor, ok := middleware.(ParamExtractor)
+		if !ok {
+			continue
+		}
+		extracted, err := extractor.ExtractParams(header)
+		if err != nil {
+			countRejection(extractor)
+			return nil, err
+		}
+		for k, v := range extracted {
+			params[k] = v
+		}
+	}
+	return params, nil
+}
+
 // Close closes all closeable middleware in the chain
 func (c Chain) Close() error {
 	for _, middleware := range c {
 		if closeable, ok := middleware.(CloseableMiddleware); ok {
 			if err := closeable.Close(); err != nil {
 				// Log error but continue closing other middleware
-				log.Printf("Error closing middleware %s: %v", middleware.Name(), err)
+				slog.Error("Error closing middleware", "middleware", middleware.Name(), "error", err)
 			}
 		}
 	}
@@ -66,7 +219,13 @@ func (c Chain) Close() error {
 
 // GetMiddlewareParams extracts middleware parameters from the request context
 func GetMiddlewareParams(r *http.Request) map[string]interface{} {
-	if params, ok := r.Context().Value(MiddlewareParamsKey).(map[string]interface{}); ok {
+	return ParamsFromContext(r.Context())
+}
+
+// ParamsFromContext extracts middleware parameters from a context, e.g. in handlers
+// that only receive the request context such as GraphQL resolvers
+func ParamsFromContext(ctx context.Context) map[string]interface{} {
+	if params, ok := ctx.Value(MiddlewareParamsKey).(map[string]interface{}); ok {
 		return params
 	}
 	return make(map[string]interface{})
@@ -74,6 +233,21 @@ func GetMiddlewareParams(r *http.Request) map[string]interface{} {
 
 // SetMiddlewareParams sets middleware parameters in the request context
 func SetMiddlewareParams(r *http.Request, params map[string]interface{}) *http.Request {
-	ctx := context.WithValue(r.Context(), MiddlewareParamsKey, params)
-	return r.WithContext(ctx)
+	return r.WithContext(ContextWithParams(r.Context(), params))
+}
+
+// ContextWithParams returns a copy of the context carrying the middleware parameters
+func ContextWithParams(ctx context.Context, params map[string]interface{}) context.Context {
+	return context.WithValue(ctx, MiddlewareParamsKey, params)
+}
+
+// ProducedParams returns the names of the parameters the middleware of the chain may inject
+func (c Chain) ProducedParams() []string {
+	var params []string
+	for _, middleware := range c {
+		if producer, ok := middleware.(ParamProducer); ok {
+			params = append(params, producer.ProducedParams()...)
+		}
+	}
+	return params
 }
@@ -3,6 +3,8 @@ package middleware
 import (
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -147,3 +149,21 @@ func TestMiddlewareChain(t *testing.T) {
 		t.Errorf("Expected tenant_id=tenant456, got %v", capturedParams["tenant_id"])
 	}
 }
+
+func TestChain_ProducedParams(t *testing.T) {
+	chain := Chain{
+		NewHTTPHeaderMiddleware(HTTPHeaderConfig{Header: "X-Tenant-ID", Parameter: "tenant_id"}),
+		NewBearerJWKSMiddleware(BearerJWKSConfig{
+			JWKSURL:       "http://localhost:3000/.well-known/jwks.json",
+			ClaimsMapping: map[string]string{"sub": "user_id", "role": "user_role"},
+		}),
+	}
+	defer chain.Close()
+
+	produced := chain.ProducedParams()
+	sort.Strings(produced)
+	expected := []string{"tenant_id", "user_id", "user_role"}
+	if !reflect.DeepEqual(produced, expected) {
+		t.Errorf("Expected %v, got %v", expected, produced)
+	}
+}
@@ -1,7 +1,9 @@
 package query
 
 import (
+	"context"
 	"fmt"
+	"sort"
 
 	"github.com/shogotsuneto/simple-query-server/internal/config"
 )
@@ -22,7 +24,15 @@ import (
 type QueryExecutor interface {
 	// Execute runs a query with the given parameters and returns results as rows of key-value pairs.
 	// Parameters are validated according to the query configuration before execution.
-	Execute(queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error)
+	// The query is cancelled when the context is done.
+	Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error)
+
+	// Describe returns the result columns of a query without returning any rows
+	Describe(ctx context.Context, queryConfig config.Query) ([]Column, error)
+
+	// BeginSnapshot starts a read-only transaction in which several queries observe the same
+	// consistent snapshot of the database. The snapshot must be closed when no longer needed.
+	BeginSnapshot(ctx context.Context) (Snapshot, error)
 
 	// Close releases database resources and closes the connection.
 	// Should be called when the executor is no longer needed.
@@ -33,6 +43,79 @@ type QueryExecutor interface {
 	IsHealthy() bool
 }
 
+// Streamer is implemented by executors that pass the rows of a query on as they are read,
+// so that large results are never held in memory as a whole
+type Streamer interface {
+	// Stream runs a query with the same semantics as QueryExecutor.Execute. columns, if not
+	// nil, is called once with the result columns before the first row, then row is called for
+	// every row in order. An error returned by a callback stops the query and is returned.
+	// Stream returns the number of rows passed to row.
+	Stream(ctx context.Context, queryConfig config.Query, params map[string]interface{}, columns func([]string) error, row func(map[string]interface{}) error) (int, error)
+}
+
+// Stream runs a query and passes its rows on as they are read if the executor is a Streamer.
+// Otherwise the whole result is executed first and then passed on row by row, with the
+// columns reported by Describe, or the sorted keys of the first row.
+func Stream(ctx context.Context, executor QueryExecutor, queryConfig config.Query, params map[string]interface{}, columns func([]string) error, row func(map[string]interface{}) error) (int, error) {
+	if streamer, ok := executor.(Streamer); ok {
+		return streamer.Stream(ctx, queryConfig, params, columns, row)
+	}
+
+	rows, err := executor.Execute(ctx, queryConfig, params)
+	if err != nil {
+		return 0, err
+	}
+	if columns != nil {
+		if err := columns(resultColumns(ctx, executor, queryConfig, rows)); err != nil {
+			return 0, err
+		}
+	}
+	for i, result := range rows {
+		if err := row(result); err != nil {
+			return i, err
+		}
+	}
+	return len(rows), nil
+}
+
+// resultColumns returns the result columns of an executed query in query order, falling back
+// to the sorted keys of the first row
+func resultColumns(ctx context.Context, executor QueryExecutor, queryConfig config.Query, rows []map[string]interface{}) []string {
+	if described, err := executor.Describe(ctx, queryConfig); err == nil && len(described) > 0 {
+		columns := make([]string, len(described))
+		for i, column := range described {
+			columns[i] = column.Name
+		}
+		return columns
+	}
+
+	var columns []string
+	if len(rows) > 0 {
+		for name := range rows[0] {
+			columns = append(columns, name)
+		}
+		sort.Strings(columns)
+	}
+	return columns
+}
+
+// Column describes a result column of a query
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // Database type name, e.g. "INT4" or "TEXT"
+}
+
+// Snapshot executes queries against one consistent, read-only view of the database.
+// A failing query does not affect the other queries of the snapshot.
+// Snapshots are bound to a single connection and are not safe for concurrent use.
+type Snapshot interface {
+	// Execute runs a query inside the snapshot, with the same semantics as QueryExecutor.Execute
+	Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error)
+
+	// Close ends the snapshot transaction and releases its connection
+	Close() error
+}
+
 // NewQueryExecutor creates a new query executor based on database type
 func NewQueryExecutor(dbConfig *config.DatabaseConfig) (QueryExecutor, error) {
 	// Database configuration is required
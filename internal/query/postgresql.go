@@ -1,16 +1,34 @@
 package query
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"regexp"
 	"strings"
 
+	"github.com/lib/pq"
 	"github.com/shogotsuneto/simple-query-server/internal/config"
 	"github.com/shogotsuneto/simple-query-server/internal/db"
+	"github.com/shogotsuneto/simple-query-server/internal/logging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracerName identifies the spans recorded for query executions
+const tracerName = "github.com/shogotsuneto/simple-query-server/internal/query"
+
+// queryNameKey is the span attribute carrying the name of the executed query
+const queryNameKey = attribute.Key("query.name")
+
+// parameterPattern matches :param references in query SQL
+var parameterPattern = regexp.MustCompile(`:(\w+)`)
+
 // PostgreSQLExecutor handles query execution against PostgreSQL databases
 type PostgreSQLExecutor struct {
 	dbManager *db.PostgreSQLManager
@@ -28,10 +46,14 @@ func NewPostgreSQLExecutor(dbConfig *config.DatabaseConfig) (*PostgreSQLExecutor
 	}, nil
 }
 
+// queryer is the subset of *sql.DB and *sql.Tx used to run queries
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
 // Execute executes a query with the given parameters
-func (e *PostgreSQLExecutor) Execute(queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
-	log.Printf("Executing PostgreSQL query: %s", queryConfig.SQL)
-	log.Printf("Parameters: %+v", params)
+func (e *PostgreSQLExecutor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	slog.DebugContext(ctx, "Executing PostgreSQL query", "query", queryConfig.Name, logging.Params(queryConfig, params))
 
 	// Validate parameters
 	if err := e.validateParameters(queryConfig, params); err != nil {
@@ -44,7 +66,152 @@ func (e *PostgreSQLExecutor) Execute(queryConfig config.Query, params map[string
 		return nil, fmt.Errorf("database connection not available")
 	}
 
-	return e.executeSQL(db, queryConfig.SQL, params)
+	return e.executeSQL(ctx, db, queryConfig, params)
+}
+
+// Stream executes a query with the given parameters and passes the result columns and then
+// every row on as they are read from the database
+func (e *PostgreSQLExecutor) Stream(ctx context.Context, queryConfig config.Query, params map[string]interface{}, columns func([]string) error, row func(map[string]interface{}) error) (int, error) {
+	slog.DebugContext(ctx, "Streaming PostgreSQL query", "query", queryConfig.Name, logging.Params(queryConfig, params))
+
+	if err := e.validateParameters(queryConfig, params); err != nil {
+		return 0, err
+	}
+
+	db := e.dbManager.GetConnection()
+	if db == nil {
+		return 0, fmt.Errorf("database connection not available")
+	}
+
+	return e.streamSQL(ctx, db, queryConfig, params, columns, row)
+}
+
+// Describe returns the result columns of a query by executing it with NULL parameters
+// wrapped in a subquery that returns no rows
+func (e *PostgreSQLExecutor) Describe(ctx context.Context, queryConfig config.Query) ([]Column, error) {
+	db := e.dbManager.GetConnection()
+	if db == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	// Bind NULL to every referenced parameter so PostgreSQL infers the types from the SQL
+	convertedSQL, args, err := e.convertSQLParameters(queryConfig.SQL, nullParameters(queryConfig.SQL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert SQL parameters: %w", err)
+	}
+
+	statement := strings.TrimRight(strings.TrimSpace(convertedSQL), ";")
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM (%s) AS described LIMIT 0", statement), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe PostgreSQL query: %w", err)
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get column types: %w", err)
+	}
+
+	columns := make([]Column, len(columnTypes))
+	for i, columnType := range columnTypes {
+		columns[i] = Column{Name: columnType.Name(), Type: columnType.DatabaseTypeName()}
+	}
+
+	return columns, nil
+}
+
+// Statement returns the SQL sent to PostgreSQL for a query, with $1, $2, ... placeholders
+func (e *PostgreSQLExecutor) Statement(queryConfig config.Query) string {
+	convertedSQL, _, _ := e.convertSQLParameters(queryConfig.SQL, nullParameters(queryConfig.SQL))
+	return convertedSQL
+}
+
+// Explain returns the plan PostgreSQL chooses for a query and its parameters in the JSON
+// format of EXPLAIN. The query itself is not executed.
+func (e *PostgreSQLExecutor) Explain(ctx context.Context, queryConfig config.Query, params map[string]interface{}) (json.RawMessage, error) {
+	if err := e.validateParameters(queryConfig, params); err != nil {
+		return nil, err
+	}
+
+	db := e.dbManager.GetConnection()
+	if db == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	convertedSQL, args, err := e.convertSQLParameters(queryConfig.SQL, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert SQL parameters: %w", err)
+	}
+
+	var plan []byte
+	if err := db.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+convertedSQL, args...).Scan(&plan); err != nil {
+		return nil, fmt.Errorf("failed to explain PostgreSQL query: %w", err)
+	}
+	return plan, nil
+}
+
+// nullParameters binds NULL to every parameter referenced in the SQL
+func nullParameters(sql string) map[string]interface{} {
+	params := make(map[string]interface{})
+	for _, match := range parameterPattern.FindAllStringSubmatch(sql, -1) {
+		params[match[1]] = nil
+	}
+	return params
+}
+
+// BeginSnapshot starts a REPEATABLE READ, READ ONLY transaction for consistent multi-query reads
+func (e *PostgreSQLExecutor) BeginSnapshot(ctx context.Context) (Snapshot, error) {
+	db := e.dbManager.GetConnection()
+	if db == nil {
+		return nil, fmt.Errorf("database connection not available")
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin snapshot transaction: %w", err)
+	}
+
+	return &postgreSQLSnapshot{executor: e, tx: tx}, nil
+}
+
+// postgreSQLSnapshot executes queries inside a single PostgreSQL transaction
+type postgreSQLSnapshot struct {
+	executor *PostgreSQLExecutor
+	tx       *sql.Tx
+}
+
+// Execute executes a query inside the snapshot transaction.
+// Each query runs under a savepoint so that a failing query does not abort the transaction.
+func (s *postgreSQLSnapshot) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	slog.DebugContext(ctx, "Executing PostgreSQL query in snapshot", "query", queryConfig.Name, logging.Params(queryConfig, params))
+
+	// Validate parameters
+	if err := s.executor.validateParameters(queryConfig, params); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.tx.ExecContext(ctx, "SAVEPOINT snapshot_query"); err != nil {
+		return nil, fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	rows, err := s.executor.executeSQL(ctx, s.tx, queryConfig, params)
+	if err != nil {
+		if _, rollbackErr := s.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT snapshot_query"); rollbackErr != nil {
+			slog.ErrorContext(ctx, "Failed to roll back to savepoint", "error", rollbackErr)
+		}
+		return nil, err
+	}
+
+	if _, err := s.tx.ExecContext(ctx, "RELEASE SAVEPOINT snapshot_query"); err != nil {
+		return nil, fmt.Errorf("failed to release savepoint: %w", err)
+	}
+
+	return rows, nil
+}
+
+// Close ends the read-only snapshot transaction
+func (s *postgreSQLSnapshot) Close() error {
+	return s.tx.Rollback()
 }
 
 // validateParameters validates that required parameters are provided with correct types
@@ -73,25 +240,42 @@ func (e *PostgreSQLExecutor) validateParameter(param config.QueryParam, params m
 		return NewClientErrorf("required parameter '%s' is missing", param.Name)
 	}
 
-	// Basic type validation
-	switch param.Type {
+	if param.IsArray() {
+		values, ok := value.([]interface{})
+		if !ok {
+			return NewClientErrorf("parameter '%s' must be an array, got %T", param.Name, value)
+		}
+		for i, element := range values {
+			if err := validateValueType(fmt.Sprintf("%s[%d]", param.Name, i), param.ElementType(), element); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return validateValueType(param.Name, param.Type, value)
+}
+
+// validateValueType performs basic type validation of a single (non-array) value
+func validateValueType(name string, paramType string, value interface{}) error {
+	switch paramType {
 	case "int":
 		switch v := value.(type) {
 		case int, int32, int64, float64:
 			// JSON numbers are parsed as float64, so we accept them for int parameters
 		default:
-			return NewClientErrorf("parameter '%s' must be an integer, got %T", param.Name, v)
+			return NewClientErrorf("parameter '%s' must be an integer, got %T", name, v)
 		}
 	case "string":
 		if _, ok := value.(string); !ok {
-			return NewClientErrorf("parameter '%s' must be a string, got %T", param.Name, value)
+			return NewClientErrorf("parameter '%s' must be a string, got %T", name, value)
 		}
 	case "float":
 		switch value.(type) {
 		case float32, float64, int, int32, int64:
 			// Accept numeric types for float parameters
 		default:
-			return NewClientErrorf("parameter '%s' must be a number, got %T", param.Name, value)
+			return NewClientErrorf("parameter '%s' must be a number, got %T", name, value)
 		}
 	}
 
@@ -103,35 +287,77 @@ func (e *PostgreSQLExecutor) IsHealthy() bool {
 	return e.dbManager.IsHealthy()
 }
 
+// DBStats returns the connection pool statistics from the database manager
+func (e *PostgreSQLExecutor) DBStats() sql.DBStats {
+	return e.dbManager.Stats()
+}
+
+// Reconnects returns the number of database reconnections from the database manager
+func (e *PostgreSQLExecutor) Reconnects() int64 {
+	return e.dbManager.Reconnects()
+}
+
 // Close closes the database connection and stops the health monitor
 func (e *PostgreSQLExecutor) Close() error {
 	return e.dbManager.Close()
 }
 
-// executeSQL executes a SQL query against the PostgreSQL database
-func (e *PostgreSQLExecutor) executeSQL(db *sql.DB, sql string, params map[string]interface{}) ([]map[string]interface{}, error) {
+// executeSQL executes a SQL query against the PostgreSQL database and returns all its rows
+func (e *PostgreSQLExecutor) executeSQL(ctx context.Context, db queryer, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	var results []map[string]interface{}
+	_, err := e.streamSQL(ctx, db, queryConfig, params, nil, func(row map[string]interface{}) error {
+		results = append(results, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// streamSQL executes a SQL query against the PostgreSQL database in a span carrying the
+// query name and statement, but not the parameter values, and passes the rows on as they are read
+func (e *PostgreSQLExecutor) streamSQL(ctx context.Context, db queryer, queryConfig config.Query, params map[string]interface{}, handleColumns func([]string) error, handleRow func(map[string]interface{}) error) (count int, err error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "db.query "+queryConfig.Name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemNamePostgreSQL, queryNameKey.String(queryConfig.Name)),
+	)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetAttributes(semconv.DBResponseReturnedRows(count))
+		}
+		span.End()
+	}()
+
 	// Convert :param syntax to PostgreSQL $1, $2, ... syntax
-	convertedSQL, args, err := e.convertSQLParameters(sql, params)
+	convertedSQL, args, err := e.convertSQLParameters(queryConfig.SQL, params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert SQL parameters: %w", err)
+		return 0, fmt.Errorf("failed to convert SQL parameters: %w", err)
 	}
+	span.SetAttributes(semconv.DBQueryText(convertedSQL))
 
-	log.Printf("Executing PostgreSQL SQL: %s", convertedSQL)
-	log.Printf("Arguments: %+v", args)
+	// Arguments are not logged; they are included with redaction when the query starts
+	slog.DebugContext(ctx, "Executing PostgreSQL SQL", "query", queryConfig.Name, "sql", convertedSQL)
 
-	rows, err := db.Query(convertedSQL, args...)
+	rows, err := db.QueryContext(ctx, convertedSQL, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute PostgreSQL query: %w", err)
+		return 0, fmt.Errorf("failed to execute PostgreSQL query: %w", err)
 	}
 	defer rows.Close()
 
 	// Get column names
 	columns, err := rows.Columns()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get column names: %w", err)
+		return 0, fmt.Errorf("failed to get column names: %w", err)
+	}
+	if handleColumns != nil {
+		if err := handleColumns(columns); err != nil {
+			return 0, err
+		}
 	}
-
-	var results []map[string]interface{}
 
 	for rows.Next() {
 		// Create slice to hold column values
@@ -142,7 +368,7 @@ func (e *PostgreSQLExecutor) executeSQL(db *sql.DB, sql string, params map[strin
 		}
 
 		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
+			return count, fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		// Convert to map
@@ -157,21 +383,23 @@ func (e *PostgreSQLExecutor) executeSQL(db *sql.DB, sql string, params map[strin
 			}
 		}
 
-		results = append(results, row)
+		if err := handleRow(row); err != nil {
+			return count, err
+		}
+		count++
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating over rows: %w", err)
+		return count, fmt.Errorf("error iterating over rows: %w", err)
 	}
 
-	return results, nil
+	return count, nil
 }
 
 // convertSQLParameters converts :param syntax to PostgreSQL $1, $2, ... syntax
 func (e *PostgreSQLExecutor) convertSQLParameters(sql string, params map[string]interface{}) (string, []interface{}, error) {
 	// Find all :param references in the SQL
-	re := regexp.MustCompile(`:(\w+)`)
-	matches := re.FindAllStringSubmatch(sql, -1)
+	matches := parameterPattern.FindAllStringSubmatch(sql, -1)
 
 	if len(matches) == 0 {
 		// No parameters to convert
@@ -199,7 +427,12 @@ func (e *PostgreSQLExecutor) convertSQLParameters(sql string, params map[string]
 		if !exists {
 			return "", nil, NewClientErrorf("parameter '%s' referenced in SQL but not provided", paramName)
 		}
-		args[i] = value
+		// Arrays are sent in PostgreSQL array literal format (e.g. for "= ANY(:ids)")
+		if values, ok := value.([]interface{}); ok {
+			args[i] = pq.Array(values)
+		} else {
+			args[i] = value
+		}
 
 		// Replace all occurrences of this parameter
 		paramPlaceholder := fmt.Sprintf(":%s", paramName)
@@ -3,6 +3,9 @@ package query
 import (
 	"reflect"
 	"testing"
+
+	"github.com/lib/pq"
+	"github.com/shogotsuneto/simple-query-server/internal/config"
 )
 
 func TestPostgreSQLExecutor_convertSQLParameters(t *testing.T) {
@@ -140,6 +143,14 @@ func TestPostgreSQLExecutor_convertSQLParameters(t *testing.T) {
 			expectedArgs: []interface{}{"", nil},
 			expectError:  false,
 		},
+		{
+			name:         "array parameter",
+			sql:          "SELECT * FROM users WHERE id = ANY(:ids)",
+			params:       map[string]interface{}{"ids": []interface{}{1, 2, 3}},
+			expectedSQL:  "SELECT * FROM users WHERE id = ANY($1)",
+			expectedArgs: []interface{}{pq.Array([]interface{}{1, 2, 3})},
+			expectError:  false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -176,3 +187,24 @@ func TestPostgreSQLExecutor_convertSQLParameters(t *testing.T) {
 		})
 	}
 }
+
+func TestPostgreSQLExecutor_Statement(t *testing.T) {
+	executor := &PostgreSQLExecutor{}
+
+	tests := []struct {
+		name     string
+		sql      string
+		expected string
+	}{
+		{name: "no parameters", sql: "SELECT * FROM users", expected: "SELECT * FROM users"},
+		{name: "repeated parameter", sql: "SELECT * FROM users WHERE id = :id OR parent_id = :id AND status = :status", expected: "SELECT * FROM users WHERE id = $1 OR parent_id = $1 AND status = $2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if statement := executor.Statement(config.Query{SQL: tt.sql}); statement != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, statement)
+			}
+		})
+	}
+}
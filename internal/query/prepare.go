@@ -0,0 +1,69 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+)
+
+// preparedStatementName is the name queries are prepared under while they are checked
+const preparedStatementName = "simple_query_server_check"
+
+// StatementTypes are the types PostgreSQL infers for the statement of a query
+type StatementTypes struct {
+	Params  []Column // Parameters in order of their placeholders, named after the :param references
+	Results []string // Types of the result columns, in order
+}
+
+// resultTypesVersion is the first PostgreSQL version (as server_version_num) whose
+// pg_prepared_statements reports result types
+const resultTypesVersion = 140000
+
+// PrepareStatement prepares the statement of a query on a connection without executing it,
+// which surfaces syntax errors and unknown tables, columns and functions, and returns the
+// parameter and result types PostgreSQL infers. The prepared statement is deallocated again.
+// Result types are reported by PostgreSQL 14 and later, and left empty on older versions.
+func PrepareStatement(ctx context.Context, conn *sql.Conn, queryConfig config.Query) (*StatementTypes, error) {
+	var version int
+	if err := conn.QueryRowContext(ctx, "SELECT current_setting('server_version_num')::int").Scan(&version); err != nil {
+		return nil, fmt.Errorf("failed to read server version: %w", err)
+	}
+
+	statement := (&PostgreSQLExecutor{}).Statement(queryConfig)
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("PREPARE %s AS %s", preparedStatementName, statement)); err != nil {
+		return nil, err
+	}
+	defer conn.ExecContext(context.Background(), "DEALLOCATE "+preparedStatementName)
+
+	var paramTypes, resultTypes []string
+	var err error
+	if version >= resultTypesVersion {
+		err = conn.QueryRowContext(ctx,
+			"SELECT parameter_types::text[], result_types::text[] FROM pg_prepared_statements WHERE name = $1",
+			preparedStatementName,
+		).Scan(pq.Array(&paramTypes), pq.Array(&resultTypes))
+	} else {
+		err = conn.QueryRowContext(ctx,
+			"SELECT parameter_types::text[] FROM pg_prepared_statements WHERE name = $1",
+			preparedStatementName,
+		).Scan(pq.Array(&paramTypes))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prepared statement types: %w", err)
+	}
+
+	// Placeholders are numbered in order of the first reference to each parameter
+	names := queryConfig.SQLParams()
+	types := &StatementTypes{Results: resultTypes}
+	for i, paramType := range paramTypes {
+		name := fmt.Sprintf("$%d", i+1)
+		if i < len(names) {
+			name = names[i]
+		}
+		types.Params = append(types.Params, Column{Name: name, Type: paramType})
+	}
+	return types, nil
+}
@@ -0,0 +1,271 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maskedValue replaces secrets in the config dump
+const maskedValue = "xxxxx"
+
+// secretKeys are the substrings of config keys whose values are masked in the config dump
+var secretKeys = []string{"password", "secret", "token"}
+
+// dsnPasswordPattern matches the password of a key=value DSN
+var dsnPasswordPattern = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// jwksRefresher is implemented by middleware validating tokens against a JWKS
+type jwksRefresher interface {
+	Name() string
+	RefreshJWKS(ctx context.Context) error
+}
+
+// adminHandler returns the handler of the admin API, requiring the configured token if any
+func (s *Server) adminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /reload", s.handleAdminReload)
+	mux.HandleFunc("GET /config", s.handleAdminConfig)
+	mux.HandleFunc("GET /db", s.handleAdminDatabase)
+	mux.HandleFunc("POST /jwks/refresh", s.handleAdminJWKSRefresh)
+	mux.HandleFunc("GET /requests", s.handleAdminRequests)
+	mux.HandleFunc("GET /queries", s.handleAdminQueries)
+	mux.HandleFunc("POST /queries/{name}/enable", s.handleAdminToggleQuery(true))
+	mux.HandleFunc("POST /queries/{name}/disable", s.handleAdminToggleQuery(false))
+	if s.slowLog != nil {
+		mux.HandleFunc("GET /slow-queries", s.handleSlowQueries)
+	}
+
+	if s.adminConfig.Token == "" {
+		return mux
+	}
+	token := []byte(s.adminConfig.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), token) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			s.writeErrorResponse(w, "Valid admin token is required", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, statusCode int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleAdminReload reloads the queries config and the server config
+func (s *Server) handleAdminReload(w http.ResponseWriter, r *http.Request) {
+	err := s.Reload()
+	if errors.Is(err, errReloadUnavailable) {
+		s.writeErrorResponse(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Configuration reload rejected, keeping the current configuration", "error", err)
+		s.writeErrorResponse(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	s.reloadMu.Lock()
+	queries := len(s.loadedQueries.Queries)
+	s.reloadMu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  "reloaded",
+		"queries": queries,
+	})
+}
+
+// handleAdminConfig returns the effective configuration with secrets masked
+func (s *Server) handleAdminConfig(w http.ResponseWriter, r *http.Request) {
+	s.reloadMu.Lock()
+	loaded, serverConfig := s.loadedQueries, s.serverConfig
+	s.reloadMu.Unlock()
+
+	response := make(map[string]interface{})
+	for key, value := range map[string]interface{}{
+		"database": s.dbConfig,
+		"server":   serverConfig,
+		"queries":  loaded,
+	} {
+		masked, err := maskConfig(value)
+		if err != nil {
+			s.writeErrorResponse(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response[key] = masked
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// maskConfig converts a config to generic values through its YAML representation, so the
+// keys are the ones of the config files, and masks the secrets in it
+func maskConfig(value interface{}) (interface{}, error) {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := yaml.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return maskSecrets(generic), nil
+}
+
+// maskSecrets masks the values of secret keys and the passwords of DSNs
+func maskSecrets(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			lower := strings.ToLower(key)
+			switch {
+			case lower == "dsn":
+				if dsn, ok := child.(string); ok {
+					value[key] = maskDSN(dsn)
+				}
+			case isSecretKey(lower):
+				if child != nil && child != "" {
+					value[key] = maskedValue
+				}
+			default:
+				value[key] = maskSecrets(child)
+			}
+		}
+	case []interface{}:
+		for i, child := range value {
+			value[i] = maskSecrets(child)
+		}
+	}
+	return value
+}
+
+// isSecretKey reports whether a lowercase config key holds a secret
+func isSecretKey(key string) bool {
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskDSN masks the password of a URL or key=value DSN
+func maskDSN(dsn string) string {
+	if parsed, err := url.Parse(dsn); err == nil && parsed.Scheme != "" && parsed.User != nil {
+		if _, hasPassword := parsed.User.Password(); hasPassword {
+			parsed.User = url.UserPassword(parsed.User.Username(), maskedValue)
+		}
+		return parsed.String()
+	}
+	return dsnPasswordPattern.ReplaceAllString(dsn, "${1}"+maskedValue)
+}
+
+// handleAdminDatabase returns the connection pool statistics of the database
+func (s *Server) handleAdminDatabase(w http.ResponseWriter, r *http.Request) {
+	if s.database == nil {
+		s.writeErrorResponse(w, "Database statistics are not available", http.StatusNotImplemented)
+		return
+	}
+
+	stats := s.database.DBStats()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"healthy":              s.database.IsHealthy(),
+		"reconnects":           s.database.Reconnects(),
+		"max_open_connections": stats.MaxOpenConnections,
+		"open_connections":     stats.OpenConnections,
+		"in_use":               stats.InUse,
+		"idle":                 stats.Idle,
+		"wait_count":           stats.WaitCount,
+		"wait_duration_ms":     stats.WaitDuration.Milliseconds(),
+		"max_idle_closed":      stats.MaxIdleClosed,
+		"max_idle_time_closed": stats.MaxIdleTimeClosed,
+		"max_lifetime_closed":  stats.MaxLifetimeClosed,
+	})
+}
+
+// handleAdminJWKSRefresh fetches the keys of every JWKS-backed middleware immediately
+func (s *Server) handleAdminJWKSRefresh(w http.ResponseWriter, r *http.Request) {
+	results := make(map[string]string)
+	failed := false
+	for _, m := range s.chain() {
+		refresher, ok := m.(jwksRefresher)
+		if !ok {
+			continue
+		}
+		if err := refresher.RefreshJWKS(r.Context()); err != nil {
+			slog.ErrorContext(r.Context(), "JWKS refresh failed", "middleware", refresher.Name(), "error", err)
+			results[refresher.Name()] = err.Error()
+			failed = true
+			continue
+		}
+		results[refresher.Name()] = "refreshed"
+	}
+
+	if len(results) == 0 {
+		s.writeErrorResponse(w, "No middleware uses a JWKS", http.StatusNotFound)
+		return
+	}
+	statusCode := http.StatusOK
+	if failed {
+		statusCode = http.StatusBadGateway
+	}
+	writeJSON(w, statusCode, map[string]interface{}{"middleware": results})
+}
+
+// handleAdminRequests lists the requests being served by the public listener
+func (s *Server) handleAdminRequests(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"requests": s.inFlight.List()})
+}
+
+// adminQuery describes whether a loaded query is served
+type adminQuery struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+// handleAdminQueries lists the loaded queries and whether they are enabled
+func (s *Server) handleAdminQueries(w http.ResponseWriter, r *http.Request) {
+	s.reloadMu.Lock()
+	states := s.queryStates()
+	s.reloadMu.Unlock()
+
+	queries := make([]adminQuery, 0, len(states))
+	for name, enabled := range states {
+		queries = append(queries, adminQuery{Name: name, Enabled: enabled})
+	}
+	sort.Slice(queries, func(i, j int) bool { return queries[i].Name < queries[j].Name })
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"queries": queries})
+}
+
+// handleAdminToggleQuery returns a handler enabling or disabling the query named in the path
+func (s *Server) handleAdminToggleQuery(enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		err := s.SetQueryEnabled(name, enabled)
+		if errors.Is(err, errUnknownQuery) {
+			s.writeErrorResponse(w, "Query '"+name+"' not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			s.writeErrorResponse(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, adminQuery{Name: name, Enabled: enabled})
+	}
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+)
+
+// adminRequest sends a request to the admin API of a server
+func adminRequest(server *Server, method string, path string, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	server.adminHandler().ServeHTTP(rr, req)
+	return rr
+}
+
+func TestAdminHandler_Token(t *testing.T) {
+	server := newTestServer(&fakeExecutor{}, map[string]config.Query{})
+	server.adminConfig = config.AdminConfig{Address: "localhost:0", Token: "s3cret"}
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{"MissingToken", "", http.StatusUnauthorized},
+		{"WrongToken", "guess", http.StatusUnauthorized},
+		{"ValidToken", "s3cret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := adminRequest(server, http.MethodGet, "/queries", tt.token)
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if rr.Code == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected WWW-Authenticate header on 401")
+			}
+		})
+	}
+}
+
+func TestAdminHandler_ToggleQuery(t *testing.T) {
+	executor := &fakeExecutor{}
+	server := newTestServer(executor, map[string]config.Query{
+		"totals": {Name: "totals", SQL: "SELECT 1"},
+		"lines":  {Name: "lines", SQL: "SELECT 2"},
+	})
+	server.queries().Composites = map[string]config.Composite{
+		"summary": {Queries: map[string]string{"header": "totals", "details": "lines"}},
+	}
+	mux, _ := server.buildMux(server.queries())
+	server.mux.Store(mux)
+
+	executeTotals := func() int {
+		rr := httptest.NewRecorder()
+		server.mux.Load().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/query/totals", strings.NewReader(`{}`)))
+		return rr.Code
+	}
+
+	if rr := adminRequest(server, http.MethodPost, "/queries/totals/disable", ""); rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 disabling query, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if code := executeTotals(); code != http.StatusNotFound {
+		t.Errorf("expected disabled query to return 404, got %d", code)
+	}
+	if _, exists := server.queries().Composites["summary"]; exists {
+		t.Error("expected composite using the disabled query to be disabled")
+	}
+
+	var listed struct {
+		Queries []adminQuery `json:"queries"`
+	}
+	rr := adminRequest(server, http.MethodGet, "/queries", "")
+	if err := json.Unmarshal(rr.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("failed to decode query list: %v", err)
+	}
+	expected := []adminQuery{{Name: "lines", Enabled: true}, {Name: "totals", Enabled: false}}
+	if len(listed.Queries) != 2 || listed.Queries[0] != expected[0] || listed.Queries[1] != expected[1] {
+		t.Errorf("expected %v, got %v", expected, listed.Queries)
+	}
+
+	if rr := adminRequest(server, http.MethodPost, "/queries/totals/enable", ""); rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 enabling query, got %d", rr.Code)
+	}
+	if code := executeTotals(); code != http.StatusOK {
+		t.Errorf("expected re-enabled query to return 200, got %d", code)
+	}
+	if _, exists := server.queries().Composites["summary"]; !exists {
+		t.Error("expected composite to be enabled again")
+	}
+
+	if rr := adminRequest(server, http.MethodPost, "/queries/unknown/disable", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown query, got %d", rr.Code)
+	}
+}
+
+func TestAdminHandler_Reload(t *testing.T) {
+	server := newTestServer(&fakeExecutor{}, map[string]config.Query{
+		"users": {Name: "users", SQL: "SELECT * FROM users"},
+	})
+
+	if rr := adminRequest(server, http.MethodPost, "/reload", ""); rr.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501 without a config path, got %d", rr.Code)
+	}
+
+	path := filepath.Join(t.TempDir(), "queries.yaml")
+	server.SetConfigPaths(path, "")
+	os.WriteFile(path, []byte(`
+queries:
+  users:
+    sql: "SELECT * FROM users"
+  orders:
+    sql: "SELECT * FROM orders"
+`), 0o644)
+	server.SetQueryEnabled("users", false)
+
+	rr := adminRequest(server, http.MethodPost, "/reload", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if _, exists := server.queries().Queries["orders"]; !exists {
+		t.Error("expected reloaded query to be served")
+	}
+	if _, exists := server.queries().Queries["users"]; exists {
+		t.Error("expected disabled query to stay disabled after reload")
+	}
+
+	// A config needing a new LISTEN channel is rejected and the served queries are kept
+	os.WriteFile(path, []byte(`
+queries:
+  events:
+    sql: "SELECT * FROM events"
+    notify_channels: [events_changed]
+`), 0o644)
+	if rr := adminRequest(server, http.MethodPost, "/reload", ""); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422, got %d", rr.Code)
+	}
+	if _, exists := server.queries().Queries["orders"]; !exists {
+		t.Error("expected served queries to be kept after a rejected reload")
+	}
+}
+
+func TestMaskConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{
+			name:     "URLDSN",
+			value:    &config.DatabaseConfig{Type: "postgres", DSN: "postgres://app:hunter2@db:5432/app"},
+			expected: `{"dsn":"postgres://app:xxxxx@db:5432/app","type":"postgres"}`,
+		},
+		{
+			name:     "KeyValueDSN",
+			value:    &config.DatabaseConfig{Type: "postgres", DSN: "host=db user=app password='hun ter2' dbname=app"},
+			expected: `{"dsn":"host=db user=app password=xxxxx dbname=app","type":"postgres"}`,
+		},
+		{
+			name: "SecretKeys",
+			value: &config.ServerConfig{
+				Admin: config.AdminConfig{Address: "localhost:9090", Token: "s3cret"},
+				Middleware: []config.MiddlewareConfig{{
+					Type:   "http-header",
+					Config: map[string]interface{}{"client_secret": "abc", "header": "X-User-ID"},
+				}},
+			},
+			expected: `{"admin":{"address":"localhost:9090","token":"xxxxx"},"middleware":[{"config":{"client_secret":"xxxxx","header":"X-User-ID"},"type":"http-header"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			masked, err := maskConfig(tt.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			actual, _ := json.Marshal(masked)
+			if string(actual) != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+const (
+	// Defaults for the batch endpoint when not set in the server configuration
+	defaultBatchMaxItems    = 50
+	defaultBatchConcurrency = 4
+)
+
+// BatchRequest represents the JSON body of a batch request
+type BatchRequest struct {
+	Items    []BatchItem `json:"items"`
+	Snapshot bool        `json:"snapshot"` // Execute all items in one consistent read-only transaction
+}
+
+// BatchItem represents a single query execution within a batch
+type BatchItem struct {
+	ID     string                 `json:"id,omitempty"` // Key of the item in the response (default: item index)
+	Query  string                 `json:"query"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// BatchItemResult represents the outcome of a single batch item
+type BatchItemResult struct {
+	Status int                      `json:"status"`
+	Rows   []map[string]interface{} `json:"rows,omitempty"`
+	Error  string                   `json:"error,omitempty"`
+}
+
+// BatchResponse represents the JSON response of a batch request, keyed by item ID
+type BatchResponse struct {
+	Results map[string]BatchItemResult `json:"results"`
+}
+
+// executeFunc matches both QueryExecutor.Execute and Snapshot.Execute
+type executeFunc func(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error)
+
+// batchConfigWithDefaults fills in defaults for unset batch settings
+func batchConfigWithDefaults(serverConfig *config.ServerConfig) config.BatchConfig {
+	var batchConfig config.BatchConfig
+	if serverConfig != nil {
+		batchConfig = serverConfig.Batch
+	}
+	if batchConfig.MaxItems == 0 {
+		batchConfig.MaxItems = defaultBatchMaxItems
+	}
+	if batchConfig.Concurrency == 0 {
+		batchConfig.Concurrency = defaultBatchConcurrency
+	}
+	return batchConfig
+}
+
+// handleBatch handles requests executing multiple queries at once
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var batchRequest BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&batchRequest); err != nil {
+		s.writeErrorResponse(w, "Invalid JSON in request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(batchRequest.Items) == 0 {
+		s.writeErrorResponse(w, "Batch must contain at least one item", http.StatusBadRequest)
+		return
+	}
+	if len(batchRequest.Items) > s.batchConfig.MaxItems {
+		s.writeErrorResponse(w, fmt.Sprintf("Batch must not contain more than %d items", s.batchConfig.MaxItems), http.StatusBadRequest)
+		return
+	}
+
+	// Assign default IDs and make sure every item can be addressed in the response
+	ids := make([]string, len(batchRequest.Items))
+	seen := make(map[string]bool)
+	for i, item := range batchRequest.Items {
+		id := item.ID
+		if id == "" {
+			id = strconv.Itoa(i)
+		}
+		if seen[id] {
+			s.writeErrorResponse(w, fmt.Sprintf("Duplicate batch item ID '%s'", id), http.StatusBadRequest)
+			return
+		}
+		seen[id] = true
+		ids[i] = id
+	}
+
+	// Middleware parameters are extracted once and applied to every item
+	middlewareParams := middleware.GetMiddlewareParams(r)
+
+	results := make([]BatchItemResult, len(batchRequest.Items))
+	if batchRequest.Snapshot {
+		snapshot, err := s.executor.BeginSnapshot(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Batch snapshot error", "error", err)
+			s.writeErrorResponse(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer snapshot.Close()
+
+		// A snapshot is bound to a single connection, so items run sequentially
+		for i, item := range batchRequest.Items {
+			results[i] = s.executeBatchItem(r.Context(), snapshot.Execute, item, middlewareParams)
+		}
+	} else {
+		var wg sync.WaitGroup
+		semaphore := make(chan struct{}, s.batchConfig.Concurrency)
+		for i, item := range batchRequest.Items {
+			wg.Add(1)
+			semaphore <- struct{}{}
+			go func(i int, item BatchItem) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+				results[i] = s.executeBatchItem(r.Context(), s.executor.Execute, item, middlewareParams)
+			}(i, item)
+		}
+		wg.Wait()
+	}
+
+	response := BatchResponse{Results: make(map[string]BatchItemResult, len(results))}
+	for i, result := range results {
+		response.Results[ids[i]] = result
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// executeBatchItem validates and executes a single batch item, reporting errors in its result
+func (s *Server) executeBatchItem(ctx context.Context, execute executeFunc, item BatchItem, middlewareParams map[string]interface{}) BatchItemResult {
+	queryConfig, exists := s.queries().Queries[item.Query]
+	if !exists {
+		return BatchItemResult{Status: http.StatusNotFound, Error: fmt.Sprintf("Query '%s' not found", item.Query)}
+	}
+
+	// Filter body parameters and merge middleware parameters, as for single query execution
+	allParams := s.filterBodyParametersByYAMLDefinition(queryConfig, item.Params)
+	for k, v := range middlewareParams {
+		allParams[k] = v
+	}
+
+	rows, err := execute(ctx, queryConfig, allParams)
+	if err != nil {
+		slog.ErrorContext(ctx, "Batch query execution error", "query", item.Query, "error", err)
+		if query.IsClientError(err) {
+			return BatchItemResult{Status: http.StatusBadRequest, Error: err.Error()}
+		}
+		return BatchItemResult{Status: http.StatusInternalServerError, Error: err.Error()}
+	}
+
+	return BatchItemResult{Status: http.StatusOK, Rows: rows}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+)
+
+func TestHandleBatch(t *testing.T) {
+	queries := map[string]config.Query{
+		"get_user": {
+			SQL:              "SELECT * FROM users WHERE id = :id AND tenant_id = :tenant_id",
+			Params:           []config.QueryParam{{Name: "id", Type: "int"}},
+			MiddlewareParams: []config.QueryParam{{Name: "tenant_id", Type: "string"}},
+		},
+		"broken": {SQL: "FAIL"},
+	}
+
+	t.Run("ExecutesItemsWithIndividualResults", func(t *testing.T) {
+		executor := &fakeExecutor{}
+		server := newTestServer(executor, queries)
+
+		body := `{"items": [
+			{"id": "a", "query": "get_user", "params": {"id": 1, "ignored": true}},
+			{"query": "get_user", "params": {}},
+			{"id": "c", "query": "unknown"},
+			{"id": "d", "query": "broken"}
+		]}`
+		req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+		req = middleware.SetMiddlewareParams(req, map[string]interface{}{"tenant_id": "t1"})
+		rr := httptest.NewRecorder()
+
+		server.handleBatch(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		var response BatchResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		expectedStatus := map[string]int{"a": 200, "1": 400, "c": 404, "d": 500}
+		for id, status := range expectedStatus {
+			result, exists := response.Results[id]
+			if !exists {
+				t.Errorf("missing result for item %s", id)
+				continue
+			}
+			if result.Status != status {
+				t.Errorf("item %s: expected status %d, got %d (%s)", id, status, result.Status, result.Error)
+			}
+		}
+
+		row := response.Results["a"].Rows[0]
+		if row["tenant_id"] != "t1" {
+			t.Errorf("expected middleware parameter to be applied, got %v", row["tenant_id"])
+		}
+		if _, exists := row["ignored"]; exists {
+			t.Errorf("expected undeclared parameter to be filtered out")
+		}
+		if executor.snapshots != 0 {
+			t.Errorf("expected no snapshot, got %d", executor.snapshots)
+		}
+	})
+
+	t.Run("SnapshotMode", func(t *testing.T) {
+		executor := &fakeExecutor{}
+		server := newTestServer(executor, queries)
+
+		body := `{"snapshot": true, "items": [{"query": "broken"}, {"query": "broken"}]}`
+		req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+
+		server.handleBatch(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if executor.snapshots != 1 || executor.calls != 2 {
+			t.Errorf("expected 2 calls in 1 snapshot, got %d calls in %d snapshots", executor.calls, executor.snapshots)
+		}
+	})
+
+	t.Run("RejectsInvalidBatches", func(t *testing.T) {
+		server := newTestServer(&fakeExecutor{}, queries)
+		server.batchConfig.MaxItems = 2
+
+		bodies := map[string]string{
+			"invalid JSON":  `{`,
+			"empty batch":   `{"items": []}`,
+			"too many":      `{"items": [{"query": "broken"}, {"query": "broken"}, {"query": "broken"}]}`,
+			"duplicate IDs": `{"items": [{"id": "x", "query": "broken"}, {"id": "x", "query": "broken"}]}`,
+		}
+		for name, body := range bodies {
+			req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+			rr := httptest.NewRecorder()
+			server.handleBatch(rr, req)
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status 400, got %d", name, rr.Code)
+			}
+		}
+	})
+}
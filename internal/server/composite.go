@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+// CompositeResponse represents the JSON response of a composite, with each sub-result under its own key
+type CompositeResponse struct {
+	Results map[string]Response `json:"results,omitempty"`
+	Error   string              `json:"error,omitempty"`
+}
+
+// handleComposite handles requests executing a composite inside one snapshot transaction
+func (s *Server) handleComposite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract composite name from path
+	name := strings.TrimPrefix(r.URL.Path, "/composite/")
+	if name == "" {
+		s.writeErrorResponse(w, "Composite name is required", http.StatusBadRequest)
+		return
+	}
+
+	// Keep one view of the queries in case they are reloaded meanwhile
+	queriesConfig := s.queries()
+	composite, exists := queriesConfig.Composites[name]
+	if !exists {
+		s.writeErrorResponse(w, fmt.Sprintf("Composite '%s' not found", name), http.StatusNotFound)
+		return
+	}
+
+	if !composite.AllowsMethod(r.Method) {
+		w.Header().Set("Allow", strings.Join(composite.AllowedMethods(), ", "))
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The request carries the parameters of all queries of the composite
+	bodyParams, err := s.readRequestParams(r, config.Query{Params: queriesConfig.CompositeParams(composite)}, false)
+	if err != nil {
+		s.writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	middlewareParams := middleware.GetMiddlewareParams(r)
+
+	snapshot, err := s.executor.BeginSnapshot(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Composite snapshot error", "error", err)
+		s.writeErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer snapshot.Close()
+
+	// All queries see the same snapshot; the composite fails as a whole if any query fails
+	response := CompositeResponse{Results: make(map[string]Response, len(composite.Queries))}
+	for key, queryName := range composite.Queries {
+		queryConfig := queriesConfig.Queries[queryName]
+
+		allParams := s.filterBodyParametersByYAMLDefinition(queryConfig, bodyParams)
+		for k, v := range middlewareParams {
+			allParams[k] = v
+		}
+
+		rows, err := snapshot.Execute(r.Context(), queryConfig, allParams)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Composite query execution error", "query", key, "error", err)
+			message := fmt.Sprintf("%s: %v", key, err)
+			if query.IsClientError(err) {
+				s.writeErrorResponse(w, message, http.StatusBadRequest)
+			} else {
+				s.writeErrorResponse(w, message, http.StatusInternalServerError)
+			}
+			return
+		}
+
+		response.Results[key] = Response{Rows: rows}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+)
+
+func TestHandleComposite(t *testing.T) {
+	queries := map[string]config.Query{
+		"totals": {
+			SQL:    "SELECT SUM(total) FROM orders WHERE user_id = :user_id",
+			Params: []config.QueryParam{{Name: "user_id", Type: "int"}},
+		},
+		"lines": {
+			SQL:    "SELECT * FROM orders WHERE user_id = :user_id AND status = :status",
+			Params: []config.QueryParam{{Name: "user_id", Type: "int"}, {Name: "status", Type: "string"}},
+		},
+		"broken": {SQL: "FAIL"},
+	}
+
+	newServer := func(executor *fakeExecutor) *Server {
+		server := newTestServer(executor, queries)
+		server.queries().Composites = map[string]config.Composite{
+			"summary": {Queries: map[string]string{"header": "totals", "details": "lines"}, Methods: []string{"GET"}},
+			"failing": {Queries: map[string]string{"header": "totals", "broken": "broken"}},
+		}
+		return server
+	}
+
+	t.Run("ExecutesQueriesInOneSnapshot", func(t *testing.T) {
+		executor := &fakeExecutor{}
+		req := httptest.NewRequest(http.MethodGet, "/composite/summary?user_id=1&status=paid", nil)
+		rr := httptest.NewRecorder()
+
+		newServer(executor).handleComposite(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		var response CompositeResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(response.Results["header"].Rows) != 1 || len(response.Results["details"].Rows) != 1 {
+			t.Errorf("expected a result under each key, got %+v", response.Results)
+		}
+		if _, exists := response.Results["header"].Rows[0]["status"]; exists {
+			t.Errorf("expected parameters not declared by a query to be filtered out")
+		}
+		if executor.snapshots != 1 || executor.calls != 2 {
+			t.Errorf("expected 2 calls in 1 snapshot, got %d calls in %d snapshots", executor.calls, executor.snapshots)
+		}
+	})
+
+	t.Run("FailsAsAWhole", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/composite/failing", strings.NewReader(`{"user_id": 1}`))
+		rr := httptest.NewRecorder()
+
+		newServer(&fakeExecutor{}).handleComposite(rr, req)
+
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("expected status 500, got %d: %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("MethodNotAllowed", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/composite/summary", strings.NewReader(`{}`))
+		rr := httptest.NewRecorder()
+
+		newServer(&fakeExecutor{}).handleComposite(rr, req)
+
+		if rr.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected status 405, got %d", rr.Code)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/composite/unknown", strings.NewReader(`{}`))
+		rr := httptest.NewRecorder()
+
+		newServer(&fakeExecutor{}).handleComposite(rr, req)
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", rr.Code)
+		}
+	})
+}
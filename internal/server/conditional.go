@@ -0,0 +1,94 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+)
+
+// writeQueryResult writes a successful query response with a strong ETag computed from
+// the encoded result, the query's Cache-Control header and, if configured, Last-Modified.
+// Conditional GET and HEAD requests whose validators still match are answered with 304.
+func writeQueryResult(w http.ResponseWriter, r *http.Request, queryConfig config.Query, rows []map[string]interface{}) {
+	var body bytes.Buffer
+	json.NewEncoder(&body).Encode(Response{Rows: rows})
+
+	sum := sha256.Sum256(body.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	if queryConfig.CacheControl != "" {
+		w.Header().Set("Cache-Control", queryConfig.CacheControl)
+	}
+	lastModified, hasLastModified := latestTimestamp(rows, queryConfig.LastModifiedColumn)
+	if hasLastModified {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && notModified(r, etag, lastModified, hasLastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(body.Bytes())
+}
+
+// notModified evaluates If-None-Match and, only when it is absent, If-Modified-Since
+func notModified(r *http.Request, etag string, lastModified time.Time, hasLastModified bool) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return etagMatches(ifNoneMatch, etag)
+	}
+
+	if ifModifiedSince := r.Header.Get("If-Modified-Since"); ifModifiedSince != "" && hasLastModified {
+		since, err := http.ParseTime(ifModifiedSince)
+		if err != nil {
+			return false
+		}
+		// HTTP dates have second precision
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
+
+// etagMatches compares an If-None-Match header value with an ETag using weak comparison
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// latestTimestamp returns the latest timestamp value of a column across all rows
+func latestTimestamp(rows []map[string]interface{}, column string) (time.Time, bool) {
+	var latest time.Time
+	found := false
+	if column == "" {
+		return latest, false
+	}
+
+	for _, row := range rows {
+		value, ok := row[column].(time.Time)
+		if !ok {
+			continue
+		}
+		if !found || value.After(latest) {
+			latest = value
+			found = true
+		}
+	}
+	return latest, found
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+)
+
+// timestampExecutor returns rows with an updated_at column
+type timestampExecutor struct {
+	fakeExecutor
+	updatedAt []time.Time
+}
+
+func (e *timestampExecutor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	rows := make([]map[string]interface{}, len(e.updatedAt))
+	for i, updatedAt := range e.updatedAt {
+		rows[i] = map[string]interface{}{"id": i, "updated_at": updatedAt}
+	}
+	return rows, nil
+}
+
+func TestConditionalRequests(t *testing.T) {
+	latest := time.Date(2025, 9, 1, 12, 30, 15, 500, time.UTC)
+	executor := &timestampExecutor{updatedAt: []time.Time{latest.Add(-time.Hour), latest}}
+	server := newTestServer(executor, map[string]config.Query{
+		"products": {
+			SQL:                "SELECT id, updated_at FROM products",
+			Methods:            []string{"GET", "POST"},
+			CacheControl:       "public, max-age=60",
+			LastModifiedColumn: "updated_at",
+		},
+	})
+
+	request := func(method string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/query/products", strings.NewReader(`{}`))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		server.handleQuery(w, req)
+		return w
+	}
+
+	first := request(http.MethodGet, nil)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || !strings.HasPrefix(etag, `"`) {
+		t.Fatalf("expected 200 with a strong ETag, got %d and %q", first.Code, etag)
+	}
+	if cacheControl := first.Header().Get("Cache-Control"); cacheControl != "public, max-age=60" {
+		t.Errorf("unexpected Cache-Control %q", cacheControl)
+	}
+	if lastModified := first.Header().Get("Last-Modified"); lastModified != "Mon, 01 Sep 2025 12:30:15 GMT" {
+		t.Errorf("unexpected Last-Modified %q", lastModified)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{"MatchingETag", http.MethodGet, map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"ETagInList", http.MethodGet, map[string]string{"If-None-Match": `"other", W/` + etag}, http.StatusNotModified},
+		{"ChangedETag", http.MethodGet, map[string]string{"If-None-Match": `"other"`}, http.StatusOK},
+		{"ETagTakesPrecedence", http.MethodGet, map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": "Mon, 01 Sep 2025 12:30:15 GMT"}, http.StatusOK},
+		{"NotModifiedSince", http.MethodGet, map[string]string{"If-Modified-Since": "Mon, 01 Sep 2025 12:30:15 GMT"}, http.StatusNotModified},
+		{"ModifiedSince", http.MethodGet, map[string]string{"If-Modified-Since": "Mon, 01 Sep 2025 12:30:14 GMT"}, http.StatusOK},
+		{"PostIsNotConditional", http.MethodPost, map[string]string{"If-None-Match": etag}, http.StatusOK},
+		{"Head", http.MethodHead, nil, http.StatusOK},
+		{"HeadMatchingETag", http.MethodHead, map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(tt.method, tt.headers)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if w.Code == http.StatusNotModified && (w.Body.Len() != 0 || w.Header().Get("ETag") != etag) {
+				t.Errorf("expected empty 304 with ETag, got %q and %q", w.Body.String(), w.Header().Get("ETag"))
+			}
+			if tt.method == http.MethodHead && (w.Body.Len() != 0 || w.Header().Get("ETag") != etag) {
+				t.Errorf("expected HEAD response with ETag and no body, got %q and %q", w.Body.String(), w.Header().Get("ETag"))
+			}
+		})
+	}
+
+	// A changed result produces a different ETag
+	executor.updatedAt = executor.updatedAt[:1]
+	if w := request(http.MethodGet, map[string]string{"If-None-Match": etag}); w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("expected 200 with a new ETag, got %d and %q", w.Code, w.Header().Get("ETag"))
+	}
+}
@@ -3,25 +3,70 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/shogotsuneto/simple-query-server/internal/audit"
+	"github.com/shogotsuneto/simple-query-server/internal/cache"
+	"github.com/shogotsuneto/simple-query-server/internal/coalesce"
+	"github.com/shogotsuneto/simple-query-server/internal/compression"
 	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/db"
+	"github.com/shogotsuneto/simple-query-server/internal/gql"
+	"github.com/shogotsuneto/simple-query-server/internal/grpcserver"
+	"github.com/shogotsuneto/simple-query-server/internal/jobs"
+	"github.com/shogotsuneto/simple-query-server/internal/logging"
+	"github.com/shogotsuneto/simple-query-server/internal/metrics"
 	"github.com/shogotsuneto/simple-query-server/internal/middleware"
 	"github.com/shogotsuneto/simple-query-server/internal/query"
+	"github.com/shogotsuneto/simple-query-server/internal/slowlog"
+	"github.com/shogotsuneto/simple-query-server/internal/tracing"
 )
 
 // Server represents the HTTP server
 type Server struct {
 	dbConfig        *config.DatabaseConfig
-	queriesConfig   *config.QueriesConfig
-	middlewareChain middleware.Chain
+	queriesConfig   atomic.Pointer[config.QueriesConfig] // executable queries: the loaded ones without disabled queries
+	middlewareChain atomic.Pointer[middleware.Chain]     // replaced when the server config is reloaded
 	executor        query.QueryExecutor
+	resultCache     *cache.Executor
+	batchConfig     config.BatchConfig
+	webSocketConfig config.WebSocketConfig
+	compression     config.CompressionConfig
+	graphqlHandler  *gql.Handler                // nil unless GraphQL is enabled
+	grpcPort        string                      // empty unless gRPC is enabled
+	notifier        notifier                    // nil unless a query declares notify or invalidate_on channels
+	jobManager      *jobs.Manager               // nil unless jobs are enabled
+	metrics         *metrics.Metrics            // nil unless metrics are enabled
+	slowLog         *slowlog.Log                // nil unless a slow query threshold is set
+	shutdownTracing func(context.Context) error // nil unless tracing is enabled
+	database        metrics.Database            // nil unless the executor reports connection pool statistics
+	notifyChannels  []string                    // channels the notifier listens on
+	adminConfig     config.AdminConfig
+	inFlight        *inFlightRequests
+	mux             atomic.Pointer[http.ServeMux] // nil until started, replaced when routes change
 	httpServer      *http.Server
+	adminServer     *http.Server  // nil unless the admin API is enabled
+	shutdown        chan struct{} // closed when shutdown starts, ending open subscriptions
 	done            chan struct{}
+
+	// Guarded by reloadMu, which serializes reloads and toggles
+	reloadMu          sync.Mutex
+	queriesConfigPath string               // empty unless reloading is enabled
+	serverConfigPath  string               // empty unless the server config is reloaded
+	serverConfig      *config.ServerConfig // nil unless a server config was given
+	loadedQueries     *config.QueriesConfig
+	disabledQueries   map[string]bool    // queries disabled at runtime, kept across reloads
+	grpcServer        *grpcserver.Server // nil unless gRPC is started
 }
 
 // Response represents the JSON response structure
@@ -32,60 +77,256 @@ type Response struct {
 
 // New creates a new Server instance
 func New(dbConfig *config.DatabaseConfig, queriesConfig *config.QueriesConfig, serverConfig *config.ServerConfig) (*Server, error) {
-	executor, err := query.NewQueryExecutor(dbConfig)
+	databaseExecutor, err := query.NewQueryExecutor(dbConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create query executor: %w", err)
 	}
 
+	// Log executions exceeding their slow query threshold, measured at the database
+	var slowQueryConfig config.SlowQueryConfig
+	if serverConfig != nil {
+		slowQueryConfig = serverConfig.SlowQueries
+	}
+	var slowLog *slowlog.Log
+	databaseQueries := databaseExecutor
+	if slowlog.Enabled(slowQueryConfig, queriesConfig) {
+		slowLog = slowlog.New(slowQueryConfig)
+		databaseQueries = slowLog.NewExecutor(databaseExecutor)
+	}
+
+	// Serve the results of queries declaring a cache from memory, and share one
+	// execution between identical concurrent calls of queries enabling coalescing
+	resultCache := cache.NewExecutor(coalesce.NewExecutor(databaseQueries))
+	var executor query.QueryExecutor = resultCache
+
+	// Export traces before any component starts recording spans, if tracing is enabled
+	var shutdownTracing func(context.Context) error
+	if serverConfig != nil && serverConfig.Tracing.Enabled {
+		shutdownTracing, err = tracing.Setup(context.Background(), serverConfig.Tracing)
+		if err != nil {
+			releaseComponents(executor, nil, nil, nil)
+			return nil, fmt.Errorf("failed to set up tracing: %w", err)
+		}
+	}
+
 	// Create middleware chain
 	middlewareChain, err := middleware.CreateMiddlewareChain(serverConfig)
 	if err != nil {
+		releaseComponents(executor, shutdownTracing, nil, nil)
 		return nil, fmt.Errorf("failed to create middleware chain: %w", err)
 	}
+	if err := queriesConfig.CheckMiddlewareParams(middlewareChain.ProducedParams()); err != nil {
+		releaseComponents(executor, shutdownTracing, middlewareChain, nil)
+		return nil, err
+	}
+
+	// Record every query execution, whichever endpoint it comes from, if metrics are enabled
+	var serverMetrics *metrics.Metrics
+	if serverConfig != nil && serverConfig.Metrics.Enabled {
+		serverMetrics = metrics.New()
+		executor = serverMetrics.NewExecutor(resultCache)
+		if database, ok := databaseExecutor.(metrics.Database); ok {
+			serverMetrics.RegisterDatabase(database)
+		}
+		serverMetrics.RegisterResultCache(resultCache)
+		serverMetrics.RegisterMiddleware(middlewareChain)
+	}
+
+	// Record executions of audited queries, whichever endpoint they come from, if auditing is enabled
+	if serverConfig != nil && serverConfig.Audit.Enabled {
+		auditLogger, err := audit.New(serverConfig.Audit, dbConfig)
+		if err != nil {
+			releaseComponents(executor, shutdownTracing, middlewareChain, nil)
+			return nil, fmt.Errorf("failed to create audit log: %w", err)
+		}
+		executor = auditLogger.NewExecutor(executor)
+	} else {
+		for name, queryConfig := range queriesConfig.Queries {
+			if queryConfig.Audit {
+				slog.Warn("Query declares audit but the audit log is not enabled", "query", name)
+			}
+		}
+	}
+
+	// Create GraphQL handler if enabled
+	var graphqlHandler *gql.Handler
+	if serverConfig != nil && serverConfig.GraphQL.Enabled {
+		graphqlHandler = gql.NewHandler(queriesConfig, executor)
+	}
+
+	// Hold a dedicated LISTEN connection if any query can be subscribed to or invalidated
+	var listener notifier
+	notifyChannels := queriesConfig.NotifyChannels()
+	if len(notifyChannels) > 0 {
+		listener = db.NewListener(dbConfig, notifyChannels)
+	}
 
-	return &Server{
+	// Start the job workers if asynchronous jobs are enabled
+	var jobManager *jobs.Manager
+	if serverConfig != nil && serverConfig.Jobs.Enabled {
+		jobManager, err = jobs.NewManager(serverConfig.Jobs, executor)
+		if err != nil {
+			releaseComponents(executor, shutdownTracing, middlewareChain, listener)
+			return nil, fmt.Errorf("failed to create job manager: %w", err)
+		}
+	}
+
+	database, _ := databaseExecutor.(metrics.Database)
+
+	s := &Server{
 		dbConfig:        dbConfig,
-		queriesConfig:   queriesConfig,
-		middlewareChain: middlewareChain,
+		serverConfig:    serverConfig,
 		executor:        executor,
+		resultCache:     resultCache,
+		batchConfig:     batchConfigWithDefaults(serverConfig),
+		webSocketConfig: webSocketConfigWithDefaults(serverConfig),
+		compression:     compressionConfig(serverConfig),
+		graphqlHandler:  graphqlHandler,
+		grpcPort:        grpcPort(serverConfig),
+		notifier:        listener,
+		jobManager:      jobManager,
+		metrics:         serverMetrics,
+		slowLog:         slowLog,
+		shutdownTracing: shutdownTracing,
+		database:        database,
+		notifyChannels:  notifyChannels,
+		adminConfig:     adminConfig(serverConfig),
+		inFlight:        newInFlightRequests(),
+		loadedQueries:   queriesConfig,
+		disabledQueries: make(map[string]bool),
+		shutdown:        make(chan struct{}),
 		done:            make(chan struct{}),
-	}, nil
+	}
+	s.queriesConfig.Store(queriesConfig)
+	s.middlewareChain.Store(&middlewareChain)
+	return s, nil
+}
+
+// releaseComponents closes what New created before failing, in the order Shutdown closes
+// them. Components not created yet are passed as nil.
+func releaseComponents(executor query.QueryExecutor, shutdownTracing func(context.Context) error, chain middleware.Chain, listener notifier) {
+	if listener != nil {
+		if err := listener.Close(); err != nil {
+			slog.Error("Notification listener close error", "error", err)
+		}
+	}
+	if chain != nil {
+		closeChain(chain)
+	}
+	if err := executor.Close(); err != nil {
+		slog.Error("Database executor close error", "error", err)
+	}
+	if shutdownTracing != nil {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("Tracing shutdown error", "error", err)
+		}
+	}
 }
 
 // Start starts the HTTP server on the specified port with graceful shutdown support
 func (s *Server) Start(ctx context.Context, port string) error {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", s.handleRoot)
-	mux.HandleFunc("/health", s.handleHealth)
-	mux.HandleFunc("/queries", s.handleListQueries)
-
-	// Wrap the query handler with middleware chain
-	queryHandler := s.middlewareChain.Wrap(s.handleQuery)
-	mux.HandleFunc("/query/", queryHandler)
+	s.reloadMu.Lock()
+	mux, routes := s.buildMux(s.queries())
+	s.mux.Store(mux)
+	s.reloadMu.Unlock()
+
+	// Dispatch to the current mux, which is replaced when the queries change
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mux.Load().ServeHTTP(w, r)
+	})
+	handler = s.inFlight.Handler(handler)
+	if s.shutdownTracing != nil {
+		handler = tracing.Handler(handler)
+	}
+	handler = logging.Handler(handler)
+	handler = withCacheBypass(handler)
+	if s.compression.Enabled {
+		handler = compression.Handler(s.compression, handler)
+	}
 
 	addr := ":" + port
 	s.httpServer = &http.Server{
 		Addr:    addr,
-		Handler: mux,
+		Handler: handler,
+	}
+	// Subscriptions never end on their own, so they are closed when shutdown starts
+	s.httpServer.RegisterOnShutdown(func() { close(s.shutdown) })
+
+	slog.Info("Server starting", "addr", addr)
+	slog.Info("Available endpoints:")
+	slog.Info("  GET  /health       - Health check")
+	slog.Info("  GET  /queries      - List available queries")
+	slog.Info("  POST /query/{name} - Execute a query (GET with query string if enabled)")
+	slog.Info("  POST /batch        - Execute multiple queries")
+	slog.Info("  POST /composite/{name} - Execute a composite in one snapshot")
+	if s.graphqlHandler != nil {
+		slog.Info("  POST /graphql      - GraphQL endpoint")
+	}
+	if s.subscriptionsEnabled() {
+		slog.Info("  GET  /subscribe/{name} - Subscribe to query results (Server-Sent Events)")
+	}
+	if s.webSocketConfig.Enabled {
+		slog.Info("  GET  /ws           - WebSocket API")
+	}
+	if s.jobManager != nil {
+		slog.Info("  POST /jobs/{name}  - Start an asynchronous export job")
+		slog.Info("  GET  /jobs/{id}    - Job status (result at /jobs/{id}/result)")
+	}
+	if s.metrics != nil {
+		slog.Info("  GET  /metrics      - Prometheus metrics")
 	}
+	for _, route := range routes {
+		slog.Info(route)
+	}
+
+	// Evict cached results when their invalidation channels are notified
+	s.invalidateCaches(ctx)
 
-	log.Printf("Server starting on %s", addr)
-	log.Printf("Available endpoints:")
-	log.Printf("  GET  /health       - Health check")
-	log.Printf("  GET  /queries      - List available queries")
-	log.Printf("  POST /query/{name} - Execute a query")
+	// Start the optional gRPC listener next to the HTTP server
+	var grpcServer *grpcserver.Server
+	if s.grpcPort != "" {
+		listener, err := net.Listen("tcp", ":"+s.grpcPort)
+		if err != nil {
+			return fmt.Errorf("failed to listen for gRPC on port %s: %w", s.grpcPort, err)
+		}
+		s.reloadMu.Lock()
+		grpcServer = grpcserver.New(s.queries(), s.executor, s.chain())
+		s.grpcServer = grpcServer
+		s.reloadMu.Unlock()
+		slog.Info("gRPC server starting", "addr", ":"+s.grpcPort)
+		go func() {
+			if err := grpcServer.Serve(ctx, listener); err != nil {
+				slog.Error("gRPC server error", "error", err)
+			}
+		}()
+	}
+
+	// Start the optional admin API on its own listener, so it can be kept off the public network
+	if s.adminConfig.Address != "" {
+		listener, err := net.Listen("tcp", s.adminConfig.Address)
+		if err != nil {
+			return fmt.Errorf("failed to listen for the admin API on %s: %w", s.adminConfig.Address, err)
+		}
+		s.adminServer = &http.Server{Handler: logging.Handler(s.adminHandler())}
+		slog.Info("Admin API starting", "addr", s.adminConfig.Address, "authenticated", s.adminConfig.Token != "")
+		go func() {
+			if err := s.adminServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+				slog.Error("Admin API error", "error", err)
+			}
+		}()
+	}
 
 	// Start server in a goroutine so we can handle shutdown
 	go func() {
 		defer close(s.done)
 		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("Server error: %v", err)
+			slog.Error("Server error", "error", err)
 		}
 	}()
 
 	// Wait for context cancellation (shutdown signal)
 	<-ctx.Done()
-	log.Printf("Shutting down server...")
+	slog.Info("Shutting down server...")
 
 	// Create shutdown context with timeout
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -93,22 +334,162 @@ func (s *Server) Start(ctx context.Context, port string) error {
 
 	// Shutdown HTTP server
 	if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
-		log.Printf("Server shutdown error: %v", err)
+		slog.Error("Server shutdown error", "error", err)
+	}
+
+	// Shutdown admin API
+	if s.adminServer != nil {
+		if err := s.adminServer.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Admin API shutdown error", "error", err)
+		}
+	}
+
+	// Stop gRPC server
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
+
+	// Cancel running jobs and remove their results
+	if s.jobManager != nil {
+		if err := s.jobManager.Close(); err != nil {
+			slog.Error("Job manager close error", "error", err)
+		}
+	}
+
+	// Close the LISTEN connection
+	if s.notifier != nil {
+		if err := s.notifier.Close(); err != nil {
+			slog.Error("Notification listener close error", "error", err)
+		}
 	}
 
 	// Close middleware chain
-	if err := s.middlewareChain.Close(); err != nil {
-		log.Printf("Middleware close error: %v", err)
+	if err := s.chain().Close(); err != nil {
+		slog.Error("Middleware close error", "error", err)
 	}
 
 	// Close database executor
 	if err := s.executor.Close(); err != nil {
-		log.Printf("Database executor close error: %v", err)
+		slog.Error("Database executor close error", "error", err)
+	}
+
+	// Export the remaining spans
+	if s.shutdownTracing != nil {
+		if err := s.shutdownTracing(shutdownCtx); err != nil {
+			slog.Error("Tracing shutdown error", "error", err)
+		}
 	}
 
 	return nil
 }
 
+// buildMux registers the endpoints and the custom routes of the queries, and returns the
+// descriptions of the custom routes
+func (s *Server) buildMux(queriesConfig *config.QueriesConfig) (*http.ServeMux, []string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleRoot)
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/queries", s.handleListQueries)
+
+	// Wrap the query handler with middleware chain
+	chain := s.chain()
+	queryHandler := chain.Wrap(s.handleQuery)
+	mux.HandleFunc("/query/", queryHandler)
+	mux.HandleFunc("/batch", chain.Wrap(s.handleBatch))
+	mux.HandleFunc("/composite/", chain.Wrap(s.handleComposite))
+	if s.graphqlHandler != nil {
+		mux.HandleFunc("/graphql", chain.Wrap(s.graphqlHandler.ServeHTTP))
+	}
+	if s.subscriptionsEnabled() {
+		mux.HandleFunc("/subscribe/", chain.Wrap(s.handleSubscribe))
+	}
+	if s.webSocketConfig.Enabled {
+		mux.HandleFunc("/ws", chain.Wrap(s.handleWebSocket))
+	}
+	if s.jobManager != nil {
+		mux.HandleFunc("/jobs/", chain.Wrap(s.handleJobs))
+	}
+	if s.metrics != nil {
+		mux.Handle("/metrics", s.metrics.Handler())
+	}
+
+	// Register custom routes declared by queries (validated when the config was loaded)
+	routes := make([]string, 0)
+	for name, queryConfig := range queriesConfig.Queries {
+		if queryConfig.Route == "" {
+			continue
+		}
+		mux.HandleFunc(queryConfig.Route, chain.Wrap(s.routeHandler(name)))
+		routes = append(routes, fmt.Sprintf("  %s - Execute query '%s'", queryConfig.Route, name))
+	}
+	sort.Strings(routes)
+
+	return mux, routes
+}
+
+// queries returns the queries that can currently be executed
+func (s *Server) queries() *config.QueriesConfig {
+	return s.queriesConfig.Load()
+}
+
+// chain returns the current middleware chain
+func (s *Server) chain() middleware.Chain {
+	if chain := s.middlewareChain.Load(); chain != nil {
+		return *chain
+	}
+	return nil
+}
+
+// subscriptionsEnabled reports whether any query can be subscribed to
+func (s *Server) subscriptionsEnabled() bool {
+	if s.notifier == nil {
+		return false
+	}
+	for _, queryConfig := range s.queries().Queries {
+		if len(queryConfig.NotifyChannels) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// grpcPort returns the configured gRPC port, if any
+func grpcPort(serverConfig *config.ServerConfig) string {
+	if serverConfig == nil {
+		return ""
+	}
+	return serverConfig.GRPC.Port
+}
+
+// adminConfig returns the configured admin API, if any
+func adminConfig(serverConfig *config.ServerConfig) config.AdminConfig {
+	if serverConfig == nil {
+		return config.AdminConfig{}
+	}
+	return serverConfig.Admin
+}
+
+// compressionConfig returns the configured response compression, if any
+func compressionConfig(serverConfig *config.ServerConfig) config.CompressionConfig {
+	if serverConfig == nil {
+		return config.CompressionConfig{}
+	}
+	return serverConfig.Compression
+}
+
+// withCacheBypass makes requests with "Cache-Control: no-cache" skip the result cache
+func withCacheBypass(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, directive := range strings.Split(r.Header.Get("Cache-Control"), ",") {
+			if strings.EqualFold(strings.TrimSpace(directive), "no-cache") {
+				r = r.WithContext(cache.WithBypass(r.Context()))
+				break
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // Done returns a channel that is closed when the server has fully shut down
 func (s *Server) Done() <-chan struct{} {
 	return s.done
@@ -121,14 +502,35 @@ func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	endpoints := map[string]string{
+		"/health":           "GET - Health check",
+		"/queries":          "GET - List available queries",
+		"/query/{name}":     "POST (or GET if enabled) - Execute a query",
+		"/batch":            "POST - Execute multiple queries",
+		"/composite/{name}": "POST (or GET if enabled) - Execute a group of queries in one snapshot",
+	}
+	if s.graphqlHandler != nil {
+		endpoints["/graphql"] = "GET, POST - GraphQL endpoint"
+	}
+	if s.subscriptionsEnabled() {
+		endpoints["/subscribe/{name}"] = "GET - Subscribe to query results (Server-Sent Events)"
+	}
+	if s.webSocketConfig.Enabled {
+		endpoints["/ws"] = "GET - WebSocket API for queries and subscriptions"
+	}
+	if s.jobManager != nil {
+		endpoints["/jobs/{name}"] = "POST - Start an asynchronous export job"
+		endpoints["/jobs/{id}"] = "GET - Job status, DELETE - Cancel job"
+		endpoints["/jobs/{id}/result"] = "GET - Download job result (?format=ndjson or csv)"
+	}
+	if s.metrics != nil {
+		endpoints["/metrics"] = "GET - Prometheus metrics"
+	}
+
 	response := map[string]interface{}{
-		"service": "simple-query-server",
-		"status":  "running",
-		"endpoints": map[string]string{
-			"/health":       "GET - Health check",
-			"/queries":      "GET - List available queries",
-			"/query/{name}": "POST - Execute a query",
-		},
+		"service":   "simple-query-server",
+		"status":    "running",
+		"endpoints": endpoints,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -149,7 +551,7 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	middlewareHealthy := true
 	middlewareStatuses := make(map[string]interface{})
 
-	for _, mw := range s.middlewareChain {
+	for _, mw := range s.chain() {
 		if healthChecker, ok := mw.(middleware.HealthChecker); ok && healthChecker.HealthCheckEnabled() {
 			isHealthy := healthChecker.IsHealthy()
 			middlewareStatuses[mw.Name()] = map[string]bool{
@@ -198,11 +600,22 @@ func (s *Server) handleListQueries(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var cacheStats map[string]cache.Stats
+	if s.resultCache != nil {
+		cacheStats = s.resultCache.Stats()
+	}
+
 	queries := make(map[string]interface{})
-	for name, query := range s.queriesConfig.Queries {
+	for name, query := range s.queries().Queries {
 		queryInfo := map[string]interface{}{
-			"sql":    query.SQL,
-			"params": query.Params, // Body parameters
+			"sql":     query.SQL,
+			"params":  query.Params, // Body parameters
+			"methods": query.AllowedMethods(),
+		}
+
+		// Add custom route if one is declared
+		if query.Route != "" {
+			queryInfo["route"] = query.Route
 		}
 
 		// Add middleware parameters if they exist
@@ -210,6 +623,25 @@ func (s *Server) handleListQueries(w http.ResponseWriter, r *http.Request) {
 			queryInfo["middleware_params"] = query.MiddlewareParams
 		}
 
+		// Add cache settings and statistics if results are cached
+		if query.Cache != nil {
+			queryInfo["cache"] = map[string]interface{}{
+				"ttl":         query.Cache.TTL.String(),
+				"max_entries": query.Cache.MaxEntries,
+				"stats":       cacheStats[name],
+			}
+		}
+
+		// Add coalescing if identical concurrent calls share one execution
+		if query.Coalesce {
+			queryInfo["coalesce"] = true
+		}
+
+		// Add notify channels if the query can be subscribed to
+		if len(query.NotifyChannels) > 0 {
+			queryInfo["notify_channels"] = query.NotifyChannels
+		}
+
 		queries[name] = queryInfo
 	}
 
@@ -217,13 +649,26 @@ func (s *Server) handleListQueries(w http.ResponseWriter, r *http.Request) {
 		"queries": queries,
 	}
 
+	// Add composites if any are configured
+	if len(s.queries().Composites) > 0 {
+		composites := make(map[string]interface{})
+		for name, composite := range s.queries().Composites {
+			composites[name] = map[string]interface{}{
+				"queries": composite.Queries,
+				"params":  s.queries().CompositeParams(composite),
+				"methods": composite.AllowedMethods(),
+			}
+		}
+		response["composites"] = composites
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
 // handleQuery handles query execution requests
 func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+	if r.Method != http.MethodPost && r.Method != http.MethodGet && r.Method != http.MethodHead {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
@@ -236,22 +681,83 @@ func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Find the query configuration
-	queryConfig, exists := s.queriesConfig.Queries[path]
+	queryConfig, exists := s.queries().Queries[path]
 	if !exists {
 		s.writeErrorResponse(w, fmt.Sprintf("Query '%s' not found", path), http.StatusNotFound)
 		return
 	}
 
+	// Each query declares which HTTP methods may execute it (POST by default)
+	if !queryConfig.AllowsMethod(r.Method) {
+		w.Header().Set("Allow", strings.Join(queryConfig.AllowedMethods(), ", "))
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bodyParams, err := s.readRequestParams(r, queryConfig, false)
+	if err != nil {
+		s.writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.executeQuery(w, r, queryConfig, bodyParams)
+}
+
+// routeHandler returns a handler for a query exposed on a custom route.
+// The mux only dispatches requests matching the route's method and path.
+func (s *Server) routeHandler(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queryConfig, exists := s.queries().Queries[name]
+		if !exists {
+			s.writeErrorResponse(w, fmt.Sprintf("Query '%s' not found", name), http.StatusNotFound)
+			return
+		}
+
+		// Parameters may all come from the path, so an empty body is accepted
+		bodyParams, err := s.readRequestParams(r, queryConfig, true)
+		if err != nil {
+			s.writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Path parameters take precedence over query string or body parameters
+		pathParams, err := parsePathParams(queryConfig, r)
+		if err != nil {
+			s.writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for k, v := range pathParams {
+			bodyParams[k] = v
+		}
+
+		s.executeQuery(w, r, queryConfig, bodyParams)
+	}
+}
+
+// readRequestParams reads the body parameters of a request: the query string for GET
+// requests and the JSON body otherwise. Only parameters defined in the YAML are kept.
+// With allowEmptyBody, a request without a body has no body parameters.
+func (s *Server) readRequestParams(r *http.Request, queryConfig config.Query, allowEmptyBody bool) (map[string]interface{}, error) {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		// Parse query string parameters, coercing them to the declared types
+		return parseQueryStringParams(queryConfig, r.URL.Query())
+	}
+
 	// Parse request body as JSON
 	var allBodyParams map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&allBodyParams); err != nil {
-		s.writeErrorResponse(w, "Invalid JSON in request body", http.StatusBadRequest)
-		return
+		if allowEmptyBody && errors.Is(err, io.EOF) {
+			return make(map[string]interface{}), nil
+		}
+		return nil, query.NewClientError("Invalid JSON in request body")
 	}
 
 	// Filter body parameters to only include those defined in the YAML configuration
-	bodyParams := s.filterBodyParametersByYAMLDefinition(queryConfig, allBodyParams)
+	return s.filterBodyParametersByYAMLDefinition(queryConfig, allBodyParams), nil
+}
 
+// executeQuery merges body and middleware parameters, executes the query and writes the response
+func (s *Server) executeQuery(w http.ResponseWriter, r *http.Request, queryConfig config.Query, bodyParams map[string]interface{}) {
 	// Extract middleware parameters from request context (set by middleware chain)
 	middlewareParams := middleware.GetMiddlewareParams(r)
 
@@ -265,22 +771,21 @@ func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Execute the query with all parameters
-	rows, err := s.executor.Execute(queryConfig, allParams)
+	rows, err := s.executor.Execute(r.Context(), queryConfig, allParams)
 	if err != nil {
-		log.Printf("Query execution error: %v", err)
 		// Check if this is a client error (invalid parameters) vs server error
 		if query.IsClientError(err) {
+			slog.WarnContext(r.Context(), "Query execution error", "query", queryConfig.Name, "error", err)
 			s.writeErrorResponse(w, err.Error(), http.StatusBadRequest)
 		} else {
+			slog.ErrorContext(r.Context(), "Query execution error", "query", queryConfig.Name, "error", err)
 			s.writeErrorResponse(w, err.Error(), http.StatusInternalServerError)
 		}
 		return
 	}
 
-	// Send successful response
-	response := Response{Rows: rows}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	// Send successful response, answering conditional requests with 304 when unchanged
+	writeQueryResult(w, r, queryConfig, rows)
 }
 
 // writeErrorResponse writes an error response
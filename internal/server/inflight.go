@@ -0,0 +1,77 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/logging"
+)
+
+// inFlightRequest describes a request being served
+type inFlightRequest struct {
+	RequestID  string    `json:"request_id,omitempty"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	RemoteAddr string    `json:"remote_addr"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs float64   `json:"duration_ms"`
+}
+
+// inFlightRequests tracks the requests being served by the public listener
+type inFlightRequests struct {
+	mu       sync.Mutex
+	next     uint64
+	requests map[uint64]*inFlightRequest
+}
+
+func newInFlightRequests() *inFlightRequests {
+	return &inFlightRequests{requests: make(map[uint64]*inFlightRequest)}
+}
+
+// Handler tracks the requests served by the next handler. It expects the request id to be
+// set by the logging handler in front of it.
+func (t *inFlightRequests) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := &inFlightRequest{
+			RequestID:  logging.RequestID(r.Context()),
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			RemoteAddr: r.RemoteAddr,
+			StartedAt:  time.Now().UTC(),
+		}
+
+		t.mu.Lock()
+		id := t.next
+		t.next++
+		t.requests[id] = request
+		t.mu.Unlock()
+
+		defer func() {
+			t.mu.Lock()
+			delete(t.requests, id)
+			t.mu.Unlock()
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// List returns the requests being served, longest running first
+func (t *inFlightRequests) List() []inFlightRequest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	requests := make([]inFlightRequest, 0, len(t.requests))
+	for _, request := range t.requests {
+		listed := *request
+		listed.DurationMs = float64(now.Sub(request.StartedAt).Microseconds()) / 1000
+		requests = append(requests, listed)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].StartedAt.Before(requests[j].StartedAt)
+	})
+	return requests
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
+// invalidateCaches evicts the cached results of queries declaring invalidate_on whenever a
+// NOTIFY arrives on one of their channels, until the context is done. Reconnects of the
+// LISTEN connection also invalidate, as notifications may have been missed. Each channel
+// has its own subscription, and the queries listening on it are looked up on every
+// notification so that reloaded queries are taken into account.
+func (s *Server) invalidateCaches(ctx context.Context) {
+	if s.notifier == nil || s.resultCache == nil {
+		return
+	}
+
+	for _, channel := range s.notifyChannels {
+		notifications, cancel := s.notifier.Subscribe([]string{channel})
+		go func(channel string) {
+			defer cancel()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-notifications:
+					for name, queryConfig := range s.queries().Queries {
+						if slices.Contains(queryConfig.InvalidateOn, channel) {
+							slog.Info("Invalidating cached results", "query", name, "channel", channel)
+							s.resultCache.Invalidate(name)
+						}
+					}
+				}
+			}
+		}(channel)
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/cache"
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+)
+
+func TestInvalidateCaches(t *testing.T) {
+	executor := &fakeExecutor{}
+	server := newTestServer(nil, map[string]config.Query{
+		"products": {
+			Name:         "products",
+			SQL:          "SELECT * FROM products",
+			Cache:        &config.QueryCache{TTL: time.Hour},
+			InvalidateOn: []string{"orders_changed"},
+		},
+	})
+	server.resultCache = cache.NewExecutor(executor)
+	server.executor = server.resultCache
+	notifier := &fakeNotifier{}
+	server.notifier = notifier
+	server.notifyChannels = []string{"orders_changed"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.invalidateCaches(ctx)
+
+	queryConfig := server.queries().Queries["products"]
+	server.executor.Execute(ctx, queryConfig, nil)
+	notifier.notify()
+
+	// Wait for the notification to be handled
+	deadline := time.Now().Add(time.Second)
+	for server.resultCache.Stats()["products"].Invalidations == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("cache was not invalidated")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	server.executor.Execute(ctx, queryConfig, nil)
+	if executor.calls != 2 {
+		t.Errorf("expected the query to be executed again after invalidation, got %d executions", executor.calls)
+	}
+}
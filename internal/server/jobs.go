@@ -0,0 +1,150 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/shogotsuneto/simple-query-server/internal/jobs"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+)
+
+// handleJobs handles asynchronous export jobs:
+//
+//	POST   /jobs/{name}       - Start a job executing the query
+//	GET    /jobs/{id}         - Job status and progress
+//	GET    /jobs/{id}/result  - Download the result (?format=ndjson or csv)
+//	DELETE /jobs/{id}         - Cancel the job, or delete its result when finished
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	if path == "" {
+		s.writeErrorResponse(w, "Query name or job ID is required", http.StatusBadRequest)
+		return
+	}
+
+	middlewareParams := middleware.GetMiddlewareParams(r)
+
+	if id, isResult := strings.CutSuffix(path, "/result"); isResult {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.writeJobResult(w, r, id, middlewareParams)
+		return
+	}
+
+	var status jobs.Status
+	var err error
+	switch r.Method {
+	case http.MethodPost:
+		s.submitJob(w, r, path, middlewareParams)
+		return
+	case http.MethodGet:
+		status, err = s.jobManager.Get(path, middlewareParams)
+	case http.MethodDelete:
+		status, err = s.jobManager.Cancel(path, middlewareParams)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		s.writeJobError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// submitJob queues a job executing the named query with the request's parameters
+func (s *Server) submitJob(w http.ResponseWriter, r *http.Request, name string, middlewareParams map[string]interface{}) {
+	queryConfig, exists := s.queries().Queries[name]
+	if !exists {
+		s.writeErrorResponse(w, fmt.Sprintf("Query '%s' not found", name), http.StatusNotFound)
+		return
+	}
+
+	bodyParams, err := s.readRequestParams(r, queryConfig, false)
+	if err != nil {
+		s.writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	allParams := make(map[string]interface{})
+	for k, v := range bodyParams {
+		allParams[k] = v
+	}
+	for k, v := range middlewareParams {
+		allParams[k] = v
+	}
+
+	status, err := s.jobManager.Submit(name, queryConfig, allParams, middlewareParams)
+	if err != nil {
+		s.writeJobError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/jobs/"+status.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(status)
+}
+
+// writeJobResult streams the spooled result of a completed job in the requested format
+func (s *Server) writeJobResult(w http.ResponseWriter, r *http.Request, id string, middlewareParams map[string]interface{}) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "ndjson"
+	}
+	if format != "ndjson" && format != "csv" {
+		s.writeErrorResponse(w, fmt.Sprintf("Unsupported format '%s' (supported: ndjson, csv)", format), http.StatusBadRequest)
+		return
+	}
+
+	status, err := s.jobManager.Get(id, middlewareParams)
+	if err != nil {
+		s.writeJobError(w, r, err)
+		return
+	}
+	file, columns, err := s.jobManager.Result(id, middlewareParams)
+	if err != nil {
+		s.writeJobError(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	filename := fmt.Sprintf("%s-%s.%s", status.Query, status.ID, format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		err = jobs.WriteCSV(w, file, columns)
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, err = io.Copy(w, file)
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to write job result", "job", id, "error", err)
+	}
+}
+
+// writeJobError maps job manager errors to HTTP error responses
+func (s *Server) writeJobError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		s.writeErrorResponse(w, "Job not found", http.StatusNotFound)
+	case errors.Is(err, jobs.ErrQueueFull):
+		s.writeErrorResponse(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, jobs.ErrNotCompleted):
+		s.writeErrorResponse(w, err.Error(), http.StatusConflict)
+	default:
+		slog.ErrorContext(r.Context(), "Job error", "error", err)
+		s.writeErrorResponse(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/jobs"
+)
+
+func TestHandleJobs(t *testing.T) {
+	server := newTestServer(&fakeExecutor{}, map[string]config.Query{
+		"report": {
+			SQL:    "SELECT * FROM orders WHERE status = :status",
+			Params: []config.QueryParam{{Name: "status", Type: "string"}},
+		},
+	})
+	manager, err := jobs.NewManager(config.JobsConfig{Directory: t.TempDir()}, server.executor)
+	if err != nil {
+		t.Fatalf("failed to create job manager: %v", err)
+	}
+	defer manager.Close()
+	server.jobManager = manager
+
+	request := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		server.handleJobs(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "/jobs/report", `{"status": "open"}`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	var status jobs.Status
+	json.NewDecoder(w.Body).Decode(&status)
+	if w.Header().Get("Location") != "/jobs/"+status.ID {
+		t.Errorf("unexpected Location header %q", w.Header().Get("Location"))
+	}
+
+	// Wait for the job to complete
+	deadline := time.Now().Add(2 * time.Second)
+	for status.State != jobs.StateCompleted {
+		if time.Now().After(deadline) {
+			t.Fatalf("job did not complete: %+v", status)
+		}
+		time.Sleep(5 * time.Millisecond)
+		json.NewDecoder(request(http.MethodGet, "/jobs/"+status.ID, "").Body).Decode(&status)
+	}
+
+	w = request(http.MethodGet, "/jobs/"+status.ID+"/result", "")
+	if w.Header().Get("Content-Type") != "application/x-ndjson" || strings.TrimSpace(w.Body.String()) != `{"status":"open"}` {
+		t.Errorf("unexpected NDJSON result %q (%s)", w.Body.String(), w.Header().Get("Content-Type"))
+	}
+
+	w = request(http.MethodGet, "/jobs/"+status.ID+"/result?format=csv", "")
+	if w.Header().Get("Content-Type") != "text/csv" || w.Body.String() != "status\nopen\n" {
+		t.Errorf("unexpected CSV result %q (%s)", w.Body.String(), w.Header().Get("Content-Type"))
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{"UnknownQuery", http.MethodPost, "/jobs/unknown", http.StatusNotFound},
+		{"UnknownJob", http.MethodGet, "/jobs/0123", http.StatusNotFound},
+		{"UnsupportedFormat", http.MethodGet, "/jobs/" + status.ID + "/result?format=xml", http.StatusBadRequest},
+		{"MethodNotAllowed", http.MethodPut, "/jobs/" + status.ID, http.StatusMethodNotAllowed},
+		{"DeleteFinishedJob", http.MethodDelete, "/jobs/" + status.ID, http.StatusOK},
+		{"DeletedJobIsGone", http.MethodGet, "/jobs/" + status.ID + "/result", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := request(tt.method, tt.path, `{}`); w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+// parseQueryStringParams converts URL query values into typed parameters using the
+// body parameter definitions of the query. Repeated keys are only accepted for array
+// parameters. Keys that are not defined in the YAML configuration are ignored.
+func parseQueryStringParams(queryConfig config.Query, values url.Values) (map[string]interface{}, error) {
+	params := make(map[string]interface{})
+
+	for _, param := range queryConfig.Params {
+		rawValues, exists := values[param.Name]
+		if !exists {
+			continue
+		}
+
+		if param.IsArray() {
+			elements := make([]interface{}, 0, len(rawValues))
+			for _, raw := range rawValues {
+				element, err := coerceParamValue(param.Name, param.ElementType(), raw)
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, element)
+			}
+			params[param.Name] = elements
+			continue
+		}
+
+		if len(rawValues) > 1 {
+			return nil, query.NewClientErrorf("parameter '%s' must not be repeated", param.Name)
+		}
+
+		value, err := coerceParamValue(param.Name, param.Type, rawValues[0])
+		if err != nil {
+			return nil, err
+		}
+		params[param.Name] = value
+	}
+
+	return params, nil
+}
+
+// parsePathParams converts the path wildcards of a custom route into typed parameters
+func parsePathParams(queryConfig config.Query, r *http.Request) (map[string]interface{}, error) {
+	params := make(map[string]interface{})
+
+	_, _, pathParams, err := config.ParseRoute(queryConfig.Route)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range pathParams {
+		for _, param := range queryConfig.Params {
+			if param.Name != name {
+				continue
+			}
+			value, err := coerceParamValue(param.Name, param.Type, r.PathValue(name))
+			if err != nil {
+				return nil, err
+			}
+			params[name] = value
+		}
+	}
+
+	return params, nil
+}
+
+// coerceParamValue converts a single string value to the declared parameter type
+func coerceParamValue(name string, paramType string, raw string) (interface{}, error) {
+	switch paramType {
+	case "int":
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, query.NewClientErrorf("parameter '%s' must be an integer, got '%s'", name, raw)
+		}
+		return value, nil
+	case "float":
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, query.NewClientErrorf("parameter '%s' must be a number, got '%s'", name, raw)
+		}
+		return value, nil
+	default:
+		// Strings and types without dedicated validation are passed through as-is
+		return raw, nil
+	}
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+func TestParseQueryStringParams(t *testing.T) {
+	queryConfig := config.Query{
+		SQL: "SELECT * FROM users WHERE id = ANY(:ids) AND name = :name AND score > :score AND age > :age",
+		Params: []config.QueryParam{
+			{Name: "ids", Type: "int[]"},
+			{Name: "name", Type: "string"},
+			{Name: "score", Type: "float"},
+			{Name: "age", Type: "int"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		rawQuery    string
+		expected    map[string]interface{}
+		expectError bool
+	}{
+		{
+			name:     "typed scalar values",
+			rawQuery: "name=foo&score=1.5&age=30",
+			expected: map[string]interface{}{"name": "foo", "score": 1.5, "age": int64(30)},
+		},
+		{
+			name:     "repeated keys fill array parameter",
+			rawQuery: "ids=1&ids=2&ids=3",
+			expected: map[string]interface{}{"ids": []interface{}{int64(1), int64(2), int64(3)}},
+		},
+		{
+			name:     "undeclared keys are ignored",
+			rawQuery: "name=foo&unknown=bar",
+			expected: map[string]interface{}{"name": "foo"},
+		},
+		{
+			name:     "missing parameters are left out",
+			rawQuery: "",
+			expected: map[string]interface{}{},
+		},
+		{
+			name:        "invalid integer",
+			rawQuery:    "age=abc",
+			expectError: true,
+		},
+		{
+			name:        "invalid array element",
+			rawQuery:    "ids=1&ids=x",
+			expectError: true,
+		},
+		{
+			name:        "repeated scalar parameter",
+			rawQuery:    "name=foo&name=bar",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.rawQuery)
+			if err != nil {
+				t.Fatalf("failed to parse query string: %v", err)
+			}
+
+			got, err := parseQueryStringParams(queryConfig, values)
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("expected error but got none")
+				}
+				if !query.IsClientError(err) {
+					t.Errorf("expected client error, got %T", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("params mismatch:\nexpected: %+v\ngot:      %+v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+)
+
+// errReloadUnavailable is returned when reloading is requested without config paths
+var errReloadUnavailable = errors.New("reloading is not available: no config paths")
+
+// errUnknownQuery is returned when toggling a query that is not loaded
+var errUnknownQuery = errors.New("query not found")
+
+// SetConfigPaths sets the files the queries and the server config are reloaded from. An
+// empty server config path leaves the middleware unchanged on reloads.
+func (s *Server) SetConfigPaths(queriesConfigPath string, serverConfigPath string) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	s.queriesConfigPath = queriesConfigPath
+	s.serverConfigPath = serverConfigPath
+}
+
+// Reload loads the queries config and the server config again. Both are validated before
+// either is applied: on error the served queries and middleware are kept. Valid queries
+// replace the served ones, with queries disabled at runtime staying disabled, and a new
+// middleware chain replaces the current one, which is then closed.
+func (s *Server) Reload() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	if s.queriesConfigPath == "" && s.serverConfigPath == "" {
+		return errReloadUnavailable
+	}
+
+	loaded := s.loadedQueries
+	if s.queriesConfigPath != "" {
+		var err error
+		loaded, err = config.LoadQueriesConfig(s.queriesConfigPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := s.checkQueries(loaded); err != nil {
+		return err
+	}
+
+	var serverConfig *config.ServerConfig
+	var chain middleware.Chain
+	if s.serverConfigPath != "" {
+		var err error
+		serverConfig, err = config.LoadServerConfig(s.serverConfigPath)
+		if err != nil {
+			return err
+		}
+		chain, err = middleware.CreateMiddlewareChain(serverConfig)
+		if err != nil {
+			return fmt.Errorf("failed to create middleware chain: %w", err)
+		}
+		if err := loaded.CheckMiddlewareParams(chain.ProducedParams()); err != nil {
+			closeChain(chain)
+			return err
+		}
+	} else if err := loaded.CheckMiddlewareParams(s.chain().ProducedParams()); err != nil {
+		return err
+	}
+
+	// The chain is swapped before the queries are applied, so the rebuilt routes use it
+	if serverConfig != nil {
+		previousChain := s.swapChain(serverConfig, chain)
+		defer closeChain(previousChain)
+	}
+	if err := s.applyQueries(loaded, s.disabledQueries); err != nil {
+		return err
+	}
+
+	if s.queriesConfigPath != "" {
+		slog.Info("Queries config reloaded", "path", s.queriesConfigPath, "queries", len(loaded.Queries))
+	}
+	if serverConfig != nil {
+		slog.Info("Server config reloaded", "path", s.serverConfigPath, "middleware", len(chain))
+	}
+	return nil
+}
+
+// swapChain makes a middleware chain current and returns the previous one. Settings other
+// than middleware are kept, as they only take effect on startup. Must be called with
+// reloadMu held.
+func (s *Server) swapChain(serverConfig *config.ServerConfig, chain middleware.Chain) middleware.Chain {
+	if s.serverConfig != nil && !sameSettings(s.serverConfig, serverConfig) {
+		slog.Warn("Server settings other than middleware changed and take effect after a restart")
+	}
+
+	effective := *serverConfig
+	if s.serverConfig != nil {
+		effective = *s.serverConfig
+		effective.Middleware = serverConfig.Middleware
+	}
+
+	previous := s.chain()
+	s.serverConfig = &effective
+	s.middlewareChain.Store(&chain)
+	if s.grpcServer != nil {
+		s.grpcServer.UpdateChain(chain)
+	}
+	if s.metrics != nil {
+		s.metrics.UpdateMiddleware(chain)
+	}
+	return previous
+}
+
+// sameSettings reports whether two server configs differ at most in their middleware
+func sameSettings(a *config.ServerConfig, b *config.ServerConfig) bool {
+	aSettings, bSettings := *a, *b
+	aSettings.Middleware, bSettings.Middleware = nil, nil
+	return reflect.DeepEqual(aSettings, bSettings)
+}
+
+// closeChain releases the resources of a replaced middleware chain. Requests still being
+// served by it keep working with what the middleware already loaded.
+func closeChain(chain middleware.Chain) {
+	if err := chain.Close(); err != nil {
+		slog.Error("Middleware close error", "error", err)
+	}
+}
+
+// SetQueryEnabled enables or disables a loaded query. Disabled queries are not served by any
+// endpoint, and composites using them are disabled too.
+func (s *Server) SetQueryEnabled(name string, enabled bool) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	if _, exists := s.loadedQueries.Queries[name]; !exists {
+		return errUnknownQuery
+	}
+	if s.disabledQueries[name] == !enabled {
+		return nil
+	}
+
+	disabled := make(map[string]bool, len(s.disabledQueries)+1)
+	for disabledName := range s.disabledQueries {
+		disabled[disabledName] = true
+	}
+	if enabled {
+		delete(disabled, name)
+	} else {
+		disabled[name] = true
+	}
+	if err := s.applyQueries(s.loadedQueries, disabled); err != nil {
+		return err
+	}
+
+	slog.Info("Query toggled", "query", name, "enabled", enabled)
+	return nil
+}
+
+// queryStates returns whether each loaded query is enabled. Must be called with reloadMu held.
+func (s *Server) queryStates() map[string]bool {
+	states := make(map[string]bool, len(s.loadedQueries.Queries))
+	for name := range s.loadedQueries.Queries {
+		states[name] = !s.disabledQueries[name]
+	}
+	return states
+}
+
+// applyQueries replaces the served queries with the loaded ones minus the disabled ones.
+// Must be called with reloadMu held.
+func (s *Server) applyQueries(loaded *config.QueriesConfig, disabled map[string]bool) error {
+	if err := s.checkQueries(loaded); err != nil {
+		return err
+	}
+
+	effective := effectiveQueries(loaded, disabled)
+
+	// Cached results of changed or removed queries are stale
+	previous := s.queries()
+	if s.resultCache != nil {
+		for name, queryConfig := range previous.Queries {
+			if current, exists := effective.Queries[name]; !exists || !reflect.DeepEqual(current, queryConfig) {
+				s.resultCache.Remove(name)
+			}
+		}
+	}
+
+	s.queriesConfig.Store(effective)
+	s.loadedQueries = loaded
+	s.disabledQueries = disabled
+
+	if s.graphqlHandler != nil {
+		s.graphqlHandler.Update(effective)
+	}
+	if s.grpcServer != nil {
+		s.grpcServer.Update(effective)
+	}
+	if s.mux.Load() != nil {
+		mux, _ := s.buildMux(effective)
+		s.mux.Store(mux)
+	}
+	return nil
+}
+
+// checkQueries checks that loaded queries can be served without a restart
+func (s *Server) checkQueries(loaded *config.QueriesConfig) error {
+	// The LISTEN connection is opened on startup for a fixed set of channels
+	for _, channel := range loaded.NotifyChannels() {
+		if !slices.Contains(s.notifyChannels, channel) {
+			return fmt.Errorf("notification channel '%s' is not listened on, a restart is required", channel)
+		}
+	}
+	return nil
+}
+
+// effectiveQueries returns the loaded queries without the disabled ones and without the
+// composites using them
+func effectiveQueries(loaded *config.QueriesConfig, disabled map[string]bool) *config.QueriesConfig {
+	if len(disabled) == 0 {
+		return loaded
+	}
+
+	effective := &config.QueriesConfig{Queries: make(map[string]config.Query, len(loaded.Queries))}
+	for name, queryConfig := range loaded.Queries {
+		if !disabled[name] {
+			effective.Queries[name] = queryConfig
+		}
+	}
+	for name, composite := range loaded.Composites {
+		usesDisabled := false
+		for _, queryName := range composite.Queries {
+			if disabled[queryName] {
+				usesDisabled = true
+				break
+			}
+		}
+		if usesDisabled {
+			continue
+		}
+		if effective.Composites == nil {
+			effective.Composites = make(map[string]config.Composite)
+		}
+		effective.Composites[name] = composite
+	}
+	return effective
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+)
+
+func TestReload_ServerConfig(t *testing.T) {
+	dir := t.TempDir()
+	queriesPath := filepath.Join(dir, "queries.yaml")
+	serverPath := filepath.Join(dir, "server.yaml")
+	os.WriteFile(queriesPath, []byte(`
+queries:
+  whoami:
+    sql: "SELECT :user_id AS user_id"
+    middleware_params:
+      - name: user_id
+        type: string
+`), 0o644)
+	writeServerConfig := func(header string) {
+		os.WriteFile(serverPath, []byte(`
+middleware:
+  - type: http-header
+    config:
+      header: `+header+`
+      parameter: user_id
+      required: true
+`), 0o644)
+	}
+	writeServerConfig("X-User-ID")
+
+	server := newTestServer(&fakeExecutor{}, map[string]config.Query{})
+	server.serverConfig = &config.ServerConfig{}
+	server.SetConfigPaths(queriesPath, serverPath)
+	mux, _ := server.buildMux(server.queries())
+	server.mux.Store(mux)
+
+	whoami := func(header string) int {
+		req := httptest.NewRequest(http.MethodPost, "/query/whoami", strings.NewReader(`{}`))
+		req.Header.Set(header, "user-1")
+		rr := httptest.NewRecorder()
+		server.mux.Load().ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if err := server.Reload(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if code := whoami("X-User-ID"); code != http.StatusOK {
+		t.Errorf("expected status 200 with the configured header, got %d", code)
+	}
+
+	writeServerConfig("X-Account-ID")
+	if err := server.Reload(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if code := whoami("X-User-ID"); code != http.StatusBadRequest {
+		t.Errorf("expected the previous header to be rejected after reload, got %d", code)
+	}
+	if code := whoami("X-Account-ID"); code != http.StatusOK {
+		t.Errorf("expected status 200 with the reloaded header, got %d", code)
+	}
+
+	// An invalid config is rejected and the current middleware and queries are kept
+	os.WriteFile(serverPath, []byte(`middleware: [{type: unknown}]`), 0o644)
+	if err := server.Reload(); err == nil {
+		t.Fatal("expected reload of an invalid server config to fail")
+	}
+	os.WriteFile(queriesPath, []byte(`queries: {broken: {sql: ""}}`), 0o644)
+	if err := server.Reload(); err == nil {
+		t.Fatal("expected reload of an invalid queries config to fail")
+	}
+	if code := whoami("X-Account-ID"); code != http.StatusOK {
+		t.Errorf("expected the current config to be kept after failed reloads, got %d", code)
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	previousInterval := watchInterval
+	watchInterval = 10 * time.Millisecond
+	t.Cleanup(func() { watchInterval = previousInterval })
+
+	dir := t.TempDir()
+	writeQueries := func(name string, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write queries file: %v", err)
+		}
+	}
+	writeQueries("users.yaml", "queries:\n  get_users:\n    sql: SELECT * FROM users\n")
+	writeQueries("orders.yaml", "queries:\n  get_orders:\n    sql_file: orders.sql\n")
+	os.WriteFile(filepath.Join(dir, "orders.sql"), []byte("SELECT * FROM orders"), 0o644)
+
+	server := newTestServer(&fakeExecutor{}, map[string]config.Query{})
+	server.SetConfigPaths(dir, "")
+	mux, _ := server.buildMux(server.queries())
+	server.mux.Store(mux)
+	if err := server.Reload(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.WatchConfig(ctx)
+
+	waitForQueries := func(expected ...string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			var names []string
+			for name, queryConfig := range server.queries().Queries {
+				names = append(names, name+": "+queryConfig.SQL)
+			}
+			sort.Strings(names)
+			if reflect.DeepEqual(names, expected) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected queries %v, got %v", expected, names)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Let the watcher take its first snapshot before changing files
+	time.Sleep(50 * time.Millisecond)
+
+	os.WriteFile(filepath.Join(dir, "orders.sql"), []byte("SELECT id FROM orders"), 0o644)
+	waitForQueries("get_orders: SELECT id FROM orders", "get_users: SELECT * FROM users")
+
+	writeQueries("products.yaml", "queries:\n  get_products:\n    sql: SELECT * FROM products\n")
+	waitForQueries("get_orders: SELECT id FROM orders", "get_products: SELECT * FROM products", "get_users: SELECT * FROM users")
+
+	if err := os.Remove(filepath.Join(dir, "orders.yaml")); err != nil {
+		t.Fatalf("failed to remove queries file: %v", err)
+	}
+	waitForQueries("get_products: SELECT * FROM products", "get_users: SELECT * FROM users")
+}
+
+func TestReadConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+	queriesPath := filepath.Join(dir, "queries.yaml")
+	os.WriteFile(queriesPath, []byte("queries: {}"), 0o644)
+	missingPath := filepath.Join(dir, "missing.yaml")
+
+	previous := map[string][]byte{missingPath: []byte("being replaced")}
+	contents := readConfigFiles([]string{queriesPath, missingPath}, previous)
+	if string(contents[queriesPath]) != "queries: {}" {
+		t.Errorf("expected the file contents, got %q", contents[queriesPath])
+	}
+	if string(contents[missingPath]) != "being replaced" {
+		t.Errorf("expected an unreadable file to keep its previous contents, got %q", contents[missingPath])
+	}
+}
+
+func TestConfigFilesEqual(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous map[string][]byte
+		current  map[string][]byte
+		expected bool
+	}{
+		{"Unchanged", map[string][]byte{"a.yaml": []byte("a")}, map[string][]byte{"a.yaml": []byte("a")}, true},
+		{"Changed", map[string][]byte{"a.yaml": []byte("a")}, map[string][]byte{"a.yaml": []byte("b")}, false},
+		{"FileAdded", map[string][]byte{"a.yaml": []byte("a")}, map[string][]byte{"a.yaml": []byte("a"), "b.yaml": []byte("b")}, false},
+		{"FileRemoved", map[string][]byte{"a.yaml": []byte("a"), "b.yaml": []byte("b")}, map[string][]byte{"a.yaml": []byte("a")}, false},
+		{"FileRenamed", map[string][]byte{"a.yaml": []byte("a")}, map[string][]byte{"b.yaml": []byte("a")}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if equal := configFilesEqual(tt.previous, tt.current); equal != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, equal)
+			}
+		})
+	}
+}
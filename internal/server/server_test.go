@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/cache"
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+// fakeExecutor is an in-memory QueryExecutor returning the merged parameters as a single row
+type fakeExecutor struct {
+	mu        sync.Mutex
+	calls     int
+	snapshots int
+}
+
+func (e *fakeExecutor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	e.mu.Lock()
+	e.calls++
+	e.mu.Unlock()
+
+	for _, param := range append(queryConfig.Params, queryConfig.MiddlewareParams...) {
+		if _, exists := params[param.Name]; !exists {
+			return nil, query.NewClientErrorf("required parameter '%s' is missing", param.Name)
+		}
+	}
+	if queryConfig.SQL == "FAIL" {
+		return nil, fmt.Errorf("database error")
+	}
+
+	row := make(map[string]interface{})
+	for k, v := range params {
+		row[k] = v
+	}
+	return []map[string]interface{}{row}, nil
+}
+
+func (e *fakeExecutor) Describe(ctx context.Context, queryConfig config.Query) ([]query.Column, error) {
+	columns := make([]query.Column, 0, len(queryConfig.Params))
+	for _, param := range queryConfig.Params {
+		columns = append(columns, query.Column{Name: param.Name, Type: "TEXT"})
+	}
+	return columns, nil
+}
+
+func (e *fakeExecutor) BeginSnapshot(ctx context.Context) (query.Snapshot, error) {
+	e.mu.Lock()
+	e.snapshots++
+	e.mu.Unlock()
+	return &fakeSnapshot{executor: e}, nil
+}
+
+func (e *fakeExecutor) Close() error {
+	return nil
+}
+
+func (e *fakeExecutor) IsHealthy() bool {
+	return true
+}
+
+// fakeSnapshot delegates to its fakeExecutor
+type fakeSnapshot struct {
+	executor *fakeExecutor
+}
+
+func (s *fakeSnapshot) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	return s.executor.Execute(ctx, queryConfig, params)
+}
+
+func (s *fakeSnapshot) Close() error {
+	return nil
+}
+
+// newTestServer creates a server backed by the given executor
+func newTestServer(executor query.QueryExecutor, queries map[string]config.Query) *Server {
+	server := &Server{
+		executor:        executor,
+		batchConfig:     batchConfigWithDefaults(nil),
+		webSocketConfig: webSocketConfigWithDefaults(nil),
+		inFlight:        newInFlightRequests(),
+		loadedQueries:   &config.QueriesConfig{Queries: queries},
+		disabledQueries: make(map[string]bool),
+		done:            make(chan struct{}),
+	}
+	server.queriesConfig.Store(server.loadedQueries)
+	return server
+}
+
+func TestWithCacheBypass(t *testing.T) {
+	executor := &fakeExecutor{}
+	server := newTestServer(cache.NewExecutor(executor), map[string]config.Query{
+		"top_products": {Name: "top_products", SQL: "SELECT", Cache: &config.QueryCache{TTL: time.Minute}},
+	})
+	handler := withCacheBypass(http.HandlerFunc(server.handleQuery))
+
+	for _, cacheControl := range []string{"", "", "max-age=0, no-cache"} {
+		req := httptest.NewRequest(http.MethodPost, "/query/top_products", strings.NewReader(`{}`))
+		req.Header.Set("Cache-Control", cacheControl)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// The second request is served from the cache, the third bypasses it
+	if executor.calls != 2 {
+		t.Errorf("expected 2 executions, got %d", executor.calls)
+	}
+}
+
+func TestRouteHandler(t *testing.T) {
+	server := newTestServer(&fakeExecutor{}, map[string]config.Query{
+		"deactivate_user": {
+			Name:   "deactivate_user",
+			SQL:    "UPDATE users SET active = false WHERE id = :id",
+			Route:  "POST /users/{id}/deactivate",
+			Params: []config.QueryParam{{Name: "id", Type: "int"}},
+		},
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /users/{id}/deactivate", server.routeHandler("deactivate_user"))
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "empty body", body: "", expectedStatus: http.StatusOK},
+		{name: "empty object", body: `{}`, expectedStatus: http.StatusOK},
+		{name: "invalid JSON", body: `{`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/users/7/deactivate", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedStatus == http.StatusOK && !strings.Contains(w.Body.String(), `"id":7`) {
+				t.Errorf("expected the path parameter in the result, got %s", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/shogotsuneto/simple-query-server/internal/slowlog"
+)
+
+// slowQueriesResponse lists recent slow query executions
+type slowQueriesResponse struct {
+	SlowQueries []slowlog.Entry `json:"slow_queries"`
+}
+
+// handleSlowQueries returns the recent slow query executions, most recent first
+func (s *Server) handleSlowQueries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slowQueriesResponse{SlowQueries: s.slowLog.Recent()})
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/cache"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+// subscriptionHeartbeatInterval is how often a comment is sent to keep idle subscriptions open
+const subscriptionHeartbeatInterval = 30 * time.Second
+
+// notifier provides change notifications for query subscriptions (implemented by db.Listener)
+type notifier interface {
+	Subscribe(channels []string) (<-chan string, func())
+	Close() error
+}
+
+// handleSubscribe streams the result of a query as Server-Sent Events. The query is
+// executed once on connect and again on every NOTIFY on one of its channels; a new
+// result event is only sent when the result differs from the previous one.
+func (s *Server) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract query name from path
+	name := strings.TrimPrefix(r.URL.Path, "/subscribe/")
+	if name == "" {
+		s.writeErrorResponse(w, "Query name is required", http.StatusBadRequest)
+		return
+	}
+
+	queryConfig, exists := s.queries().Queries[name]
+	if !exists {
+		s.writeErrorResponse(w, fmt.Sprintf("Query '%s' not found", name), http.StatusNotFound)
+		return
+	}
+
+	if len(queryConfig.NotifyChannels) == 0 || s.notifier == nil {
+		s.writeErrorResponse(w, fmt.Sprintf("Query '%s' does not support subscriptions", name), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.writeErrorResponse(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	bodyParams, err := s.readRequestParams(r, queryConfig, false)
+	if err != nil {
+		s.writeErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Each subscriber executes the query with its own middleware parameters
+	allParams := make(map[string]interface{})
+	for k, v := range bodyParams {
+		allParams[k] = v
+	}
+	for k, v := range middleware.GetMiddlewareParams(r) {
+		allParams[k] = v
+	}
+
+	// Subscribe before the initial execution so that no change is missed in between
+	notifications, cancel := s.notifier.Subscribe(queryConfig.NotifyChannels)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// Re-executions follow a change notification, so they skip cached results
+	execute := func() ([]map[string]interface{}, error) {
+		return s.executor.Execute(cache.WithBypass(r.Context()), queryConfig, allParams)
+	}
+	publish := func(rows []map[string]interface{}, err error) bool {
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Subscription query execution error", "query", name, "error", err)
+			writeEvent(w, "error", Response{Error: err.Error()})
+			flusher.Flush()
+			// Invalid parameters will not become valid, so the subscription ends
+			return !query.IsClientError(err)
+		}
+		writeEvent(w, "result", Response{Rows: rows})
+		flusher.Flush()
+		return true
+	}
+	heartbeat := func() {
+		fmt.Fprint(w, ": keep-alive\n\n")
+		flusher.Flush()
+	}
+
+	s.watchQuery(r.Context(), notifications, execute, publish, heartbeat)
+}
+
+// watchQuery executes a query and re-executes it on every notification, passing each
+// changed result (or error) to publish. It returns when the context is done, the server
+// shuts down or publish returns false. The heartbeat function is optional.
+func (s *Server) watchQuery(ctx context.Context, notifications <-chan string, execute func() ([]map[string]interface{}, error), publish func(rows []map[string]interface{}, err error) bool, heartbeat func()) {
+	var lastResult []byte
+	refresh := func() bool {
+		rows, err := execute()
+		if err != nil {
+			return publish(nil, err)
+		}
+
+		// Only changed results are published
+		result, _ := json.Marshal(rows)
+		if lastResult != nil && bytes.Equal(result, lastResult) {
+			return true
+		}
+		lastResult = result
+		return publish(rows, nil)
+	}
+
+	if !refresh() {
+		return
+	}
+
+	var heartbeatC <-chan time.Time
+	if heartbeat != nil {
+		ticker := time.NewTicker(subscriptionHeartbeatInterval)
+		defer ticker.Stop()
+		heartbeatC = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.shutdown:
+			return
+		case <-notifications:
+			if !refresh() {
+				return
+			}
+		case <-heartbeatC:
+			heartbeat()
+		}
+	}
+}
+
+// writeEvent writes a Server-Sent Event with a JSON payload
+func writeEvent(w http.ResponseWriter, event string, payload interface{}) {
+	data, _ := json.Marshal(payload)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/cache"
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+)
+
+// fakeNotifier hands out one subscription channel that tests can signal directly
+type fakeNotifier struct {
+	mu            sync.Mutex
+	notifications chan string
+}
+
+func (n *fakeNotifier) Subscribe(channels []string) (<-chan string, func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifications = make(chan string, 1)
+	return n.notifications, func() {}
+}
+
+func (n *fakeNotifier) Close() error {
+	return nil
+}
+
+func (n *fakeNotifier) notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifications <- "orders_changed"
+}
+
+// versionExecutor returns the current version as a single row
+type versionExecutor struct {
+	fakeExecutor
+	version atomic.Int64
+}
+
+func (e *versionExecutor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	if _, err := e.fakeExecutor.Execute(ctx, queryConfig, params); err != nil {
+		return nil, err
+	}
+	return []map[string]interface{}{{"version": e.version.Load()}}, nil
+}
+
+// readEvent reads the next event from a Server-Sent Events stream, skipping comments
+func readEvent(t *testing.T, reader *bufio.Reader) (string, string) {
+	t.Helper()
+	var event, data string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read event: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && event != "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestHandleSubscribe(t *testing.T) {
+	executor := &versionExecutor{}
+	notifier := &fakeNotifier{}
+	server := newTestServer(executor, map[string]config.Query{
+		"orders": {
+			SQL:            "SELECT count(*) FROM orders WHERE status = :status",
+			Params:         []config.QueryParam{{Name: "status", Type: "string"}},
+			NotifyChannels: []string{"orders_changed"},
+		},
+		"users": {SQL: "SELECT * FROM users"},
+	})
+	server.notifier = notifier
+
+	httpServer := httptest.NewServer(http.HandlerFunc(server.handleSubscribe))
+	defer httpServer.Close()
+
+	t.Run("StreamsChangedResults", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		request, _ := http.NewRequestWithContext(ctx, http.MethodGet, httpServer.URL+"/subscribe/orders?status=open", nil)
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+		defer response.Body.Close()
+
+		if contentType := response.Header.Get("Content-Type"); contentType != "text/event-stream" {
+			t.Fatalf("expected text/event-stream, got %q", contentType)
+		}
+
+		reader := bufio.NewReader(response.Body)
+		if event, data := readEvent(t, reader); event != "result" || data != `{"rows":[{"version":0}]}` {
+			t.Fatalf("unexpected initial event %q: %s", event, data)
+		}
+
+		// An unchanged result is not sent again
+		notifier.notify()
+		executor.version.Store(1)
+		notifier.notify()
+
+		if event, data := readEvent(t, reader); event != "result" || data != `{"rows":[{"version":1}]}` {
+			t.Fatalf("unexpected event %q: %s", event, data)
+		}
+	})
+
+	t.Run("CachedQuery", func(t *testing.T) {
+		executor := &versionExecutor{}
+		notifier := &fakeNotifier{}
+		server := newTestServer(cache.NewExecutor(executor), map[string]config.Query{
+			"orders": {
+				Name:           "orders",
+				SQL:            "SELECT count(*) FROM orders",
+				Cache:          &config.QueryCache{TTL: time.Minute},
+				NotifyChannels: []string{"orders_changed"},
+			},
+		})
+		server.notifier = notifier
+
+		httpServer := httptest.NewServer(http.HandlerFunc(server.handleSubscribe))
+		defer httpServer.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		request, _ := http.NewRequestWithContext(ctx, http.MethodGet, httpServer.URL+"/subscribe/orders", nil)
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+		defer response.Body.Close()
+
+		reader := bufio.NewReader(response.Body)
+		if event, data := readEvent(t, reader); event != "result" || data != `{"rows":[{"version":0}]}` {
+			t.Fatalf("unexpected initial event %q: %s", event, data)
+		}
+
+		// The change is sent although the previous result is still cached
+		executor.version.Store(1)
+		notifier.notify()
+
+		if event, data := readEvent(t, reader); event != "result" || data != `{"rows":[{"version":1}]}` {
+			t.Fatalf("unexpected event %q: %s", event, data)
+		}
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{"UnknownQuery", http.MethodGet, "/subscribe/unknown", http.StatusNotFound},
+		{"NoNotifyChannels", http.MethodGet, "/subscribe/users", http.StatusBadRequest},
+		{"PostNotAllowed", http.MethodPost, "/subscribe/orders", http.StatusMethodNotAllowed},
+		{"MissingParameter", http.MethodGet, "/subscribe/orders", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				server.handleSubscribe(w, req)
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("handler did not return")
+			}
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+)
+
+// watchInterval is how often the config files are checked for changes
+var watchInterval = 2 * time.Second
+
+// WatchConfig reloads the configuration whenever the contents of the queries config files,
+// the SQL files they refer to or the server config change, or when YAML files are added to
+// or removed from a queries config directory, until the context is done. Files are polled
+// rather than watched for events, so changes are picked up whether editors write in place or
+// replace the file, as happens with mounted Kubernetes ConfigMaps.
+func (s *Server) WatchConfig(ctx context.Context) {
+	contents := readConfigFiles(s.configFiles(), nil)
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	slog.Info("Watching config files for changes", "files", len(contents))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := readConfigFiles(s.configFiles(), contents)
+		if configFilesEqual(contents, current) {
+			continue
+		}
+		contents = current
+
+		slog.Info("Config file change detected, reloading")
+		if err := s.Reload(); err != nil {
+			slog.Error("Configuration reload failed, keeping the current configuration", "error", err)
+		}
+	}
+}
+
+// configFiles returns the files the configuration is loaded from: the YAML files the queries
+// config path currently refers to, the SQL files of the loaded queries and the server config.
+// YAML files of the loaded queries that the path no longer refers to are left out, so their
+// removal is detected.
+func (s *Server) configFiles() []string {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	seen := make(map[string]bool)
+	if s.queriesConfigPath != "" {
+		// On error the YAML files are listed again on the next check
+		files, _ := config.QueriesConfigFiles(s.queriesConfigPath)
+		for _, file := range files {
+			seen[file] = true
+		}
+		for _, file := range s.loadedQueries.Files {
+			if ext := filepath.Ext(file); ext != ".yaml" && ext != ".yml" {
+				seen[file] = true
+			}
+		}
+	}
+	if s.serverConfigPath != "" {
+		seen[s.serverConfigPath] = true
+	}
+
+	files := make([]string, 0, len(seen))
+	for file := range seen {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// readConfigFiles returns the contents of the listed config files. A file being replaced may
+// be missing for a moment, so listed files that cannot be read keep their previous contents,
+// if any. Files that are no longer listed are dropped.
+func readConfigFiles(paths []string, previous map[string][]byte) map[string][]byte {
+	contents := make(map[string][]byte, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if data, exists := previous[path]; exists {
+				contents[path] = data
+			}
+			continue
+		}
+		contents[path] = data
+	}
+	return contents
+}
+
+// configFilesEqual reports whether two reads of the config files found the same files with
+// the same contents
+func configFilesEqual(previous map[string][]byte, current map[string][]byte) bool {
+	if len(previous) != len(current) {
+		return false
+	}
+	for path, data := range current {
+		previousData, exists := previous[path]
+		if !exists || !bytes.Equal(previousData, data) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,355 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/shogotsuneto/simple-query-server/internal/cache"
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+const (
+	// Defaults for the WebSocket endpoint when not set in the server configuration
+	defaultWebSocketMaxConcurrency   = 4
+	defaultWebSocketMaxSubscriptions = 16
+
+	// Connection settings of the WebSocket endpoint
+	wsMaxMessageSize = 1 << 20
+	wsSendBuffer     = 16
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsPingInterval   = wsPongWait * 9 / 10
+)
+
+// Message types of the WebSocket protocol
+const (
+	wsTypeQuery       = "query"
+	wsTypeSubscribe   = "subscribe"
+	wsTypeUnsubscribe = "unsubscribe"
+	wsTypeResult      = "result"
+	wsTypeError       = "error"
+	wsTypeComplete    = "complete"
+)
+
+// WSRequest represents a message sent by a WebSocket client
+type WSRequest struct {
+	ID     string                 `json:"id"`   // Correlates responses with the request
+	Type   string                 `json:"type"` // "query", "subscribe" or "unsubscribe"
+	Query  string                 `json:"query,omitempty"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// WSResponse represents a message sent to a WebSocket client
+type WSResponse struct {
+	ID     string                   `json:"id,omitempty"`
+	Type   string                   `json:"type"` // "result", "error" or "complete"
+	Status int                      `json:"status,omitempty"`
+	Rows   []map[string]interface{} `json:"rows,omitempty"`
+	Error  string                   `json:"error,omitempty"`
+}
+
+// webSocketConfigWithDefaults fills in defaults for unset WebSocket settings
+func webSocketConfigWithDefaults(serverConfig *config.ServerConfig) config.WebSocketConfig {
+	var webSocketConfig config.WebSocketConfig
+	if serverConfig != nil {
+		webSocketConfig = serverConfig.WebSocket
+	}
+	if webSocketConfig.MaxConcurrency == 0 {
+		webSocketConfig.MaxConcurrency = defaultWebSocketMaxConcurrency
+	}
+	if webSocketConfig.MaxSubscriptions == 0 {
+		webSocketConfig.MaxSubscriptions = defaultWebSocketMaxSubscriptions
+	}
+	return webSocketConfig
+}
+
+// wsConnection holds the state of a single WebSocket connection
+type wsConnection struct {
+	server           *Server
+	conn             *websocket.Conn
+	middlewareParams map[string]interface{}
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	send      chan WSResponse
+	semaphore chan struct{} // limits concurrent executions of this connection
+	wg        sync.WaitGroup
+
+	mu            sync.Mutex
+	subscriptions map[string]context.CancelFunc
+}
+
+// handleWebSocket upgrades the request to a WebSocket connection carrying many query
+// executions and subscriptions. Middleware runs once for the upgrade request, and its
+// parameters apply to every message of the connection.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{CheckOrigin: s.checkWebSocketOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied with an HTTP error
+		slog.WarnContext(r.Context(), "WebSocket upgrade error", "error", err)
+		return
+	}
+
+	// The connection outlives the upgrade request, but keeps its values such as the request id
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	c := &wsConnection{
+		server:           s,
+		conn:             conn,
+		middlewareParams: middleware.GetMiddlewareParams(r),
+		ctx:              ctx,
+		cancel:           cancel,
+		send:             make(chan WSResponse, wsSendBuffer),
+		semaphore:        make(chan struct{}, s.webSocketConfig.MaxConcurrency),
+		subscriptions:    make(map[string]context.CancelFunc),
+	}
+
+	go c.writeLoop()
+	c.readLoop()
+
+	// Stop in-flight executions and subscriptions before closing the connection
+	c.cancel()
+	c.wg.Wait()
+	conn.Close()
+}
+
+// checkWebSocketOrigin allows same-origin requests and the configured cross-origin hosts
+func (s *Server) checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range s.webSocketConfig.AllowedOrigins {
+		if strings.EqualFold(allowed, origin) || strings.EqualFold(allowed, u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// readLoop reads and dispatches messages until the connection fails or is closed.
+// Dispatching blocks while the connection is at its concurrency limit, so a client
+// sending faster than its queries complete is slowed down instead of queueing work.
+func (c *wsConnection) readLoop() {
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				slog.WarnContext(c.ctx, "WebSocket read error", "error", err)
+			}
+			return
+		}
+
+		var request WSRequest
+		if err := json.Unmarshal(data, &request); err != nil {
+			c.reply(WSResponse{Type: wsTypeError, Status: http.StatusBadRequest, Error: "Invalid JSON message"})
+			continue
+		}
+
+		if !c.dispatch(request) {
+			return
+		}
+	}
+}
+
+// writeLoop is the only writer of the connection. It sends responses and keepalive pings
+// and closes the connection when the server shuts down.
+func (c *wsConnection) writeLoop() {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.server.shutdown:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"))
+			c.conn.Close()
+			return
+		case response := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteJSON(response); err != nil {
+				slog.WarnContext(c.ctx, "WebSocket write error", "error", err)
+				c.conn.Close()
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// reply queues a response, blocking while the send buffer is full. It reports false
+// once the connection is closing.
+func (c *wsConnection) reply(response WSResponse) bool {
+	select {
+	case c.send <- response:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
+
+// acquire waits for an execution slot of the connection
+func (c *wsConnection) acquire() bool {
+	select {
+	case c.semaphore <- struct{}{}:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
+
+// release frees an execution slot of the connection
+func (c *wsConnection) release() {
+	<-c.semaphore
+}
+
+// dispatch handles a single client message. It reports false once the connection is closing.
+func (c *wsConnection) dispatch(request WSRequest) bool {
+	if request.ID == "" {
+		return c.reply(WSResponse{Type: wsTypeError, Status: http.StatusBadRequest, Error: "Message id is required"})
+	}
+
+	switch request.Type {
+	case wsTypeQuery:
+		if !c.acquire() {
+			return false
+		}
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			defer c.release()
+			item := BatchItem{ID: request.ID, Query: request.Query, Params: request.Params}
+			result := c.server.executeBatchItem(c.ctx, c.server.executor.Execute, item, c.middlewareParams)
+			c.reply(wsResponseFromResult(request.ID, result))
+		}()
+		return true
+	case wsTypeSubscribe:
+		return c.subscribe(request)
+	case wsTypeUnsubscribe:
+		c.mu.Lock()
+		cancel, exists := c.subscriptions[request.ID]
+		c.mu.Unlock()
+		if !exists {
+			return c.reply(WSResponse{ID: request.ID, Type: wsTypeError, Status: http.StatusNotFound, Error: fmt.Sprintf("Subscription '%s' not found", request.ID)})
+		}
+		// The subscription sends the complete message once it has stopped
+		cancel()
+		return true
+	default:
+		return c.reply(WSResponse{ID: request.ID, Type: wsTypeError, Status: http.StatusBadRequest, Error: fmt.Sprintf("Unknown message type '%s'", request.Type)})
+	}
+}
+
+// subscribe starts a subscription streaming changed results under the request ID
+func (c *wsConnection) subscribe(request WSRequest) bool {
+	s := c.server
+	queryConfig, exists := s.queries().Queries[request.Query]
+	if !exists {
+		return c.reply(WSResponse{ID: request.ID, Type: wsTypeError, Status: http.StatusNotFound, Error: fmt.Sprintf("Query '%s' not found", request.Query)})
+	}
+	if len(queryConfig.NotifyChannels) == 0 || s.notifier == nil {
+		return c.reply(WSResponse{ID: request.ID, Type: wsTypeError, Status: http.StatusBadRequest, Error: fmt.Sprintf("Query '%s' does not support subscriptions", request.Query)})
+	}
+
+	c.mu.Lock()
+	if _, exists := c.subscriptions[request.ID]; exists {
+		c.mu.Unlock()
+		return c.reply(WSResponse{ID: request.ID, Type: wsTypeError, Status: http.StatusBadRequest, Error: fmt.Sprintf("Subscription '%s' already exists", request.ID)})
+	}
+	if len(c.subscriptions) >= s.webSocketConfig.MaxSubscriptions {
+		c.mu.Unlock()
+		return c.reply(WSResponse{ID: request.ID, Type: wsTypeError, Status: http.StatusTooManyRequests, Error: fmt.Sprintf("Connection must not have more than %d subscriptions", s.webSocketConfig.MaxSubscriptions)})
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.subscriptions[request.ID] = cancel
+	c.mu.Unlock()
+
+	allParams := s.filterBodyParametersByYAMLDefinition(queryConfig, request.Params)
+	for k, v := range c.middlewareParams {
+		allParams[k] = v
+	}
+
+	notifications, unsubscribe := s.notifier.Subscribe(queryConfig.NotifyChannels)
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer func() {
+			unsubscribe()
+			c.mu.Lock()
+			delete(c.subscriptions, request.ID)
+			c.mu.Unlock()
+			cancel()
+		}()
+
+		// Re-executions share the connection's execution slots with one-off queries. They
+		// follow a change notification, so they skip cached results.
+		execute := func() ([]map[string]interface{}, error) {
+			if !c.acquire() {
+				return nil, ctx.Err()
+			}
+			defer c.release()
+			return s.executor.Execute(cache.WithBypass(ctx), queryConfig, allParams)
+		}
+		publish := func(rows []map[string]interface{}, err error) bool {
+			if ctx.Err() != nil {
+				return false
+			}
+			if err != nil {
+				slog.ErrorContext(ctx, "WebSocket subscription error", "query", request.Query, "error", err)
+				status := http.StatusInternalServerError
+				if query.IsClientError(err) {
+					status = http.StatusBadRequest
+				}
+				c.reply(WSResponse{ID: request.ID, Type: wsTypeError, Status: status, Error: err.Error()})
+				// Invalid parameters will not become valid, so the subscription ends
+				return status != http.StatusBadRequest
+			}
+			return c.reply(WSResponse{ID: request.ID, Type: wsTypeResult, Status: http.StatusOK, Rows: rows})
+		}
+
+		s.watchQuery(ctx, notifications, execute, publish, nil)
+		c.reply(WSResponse{ID: request.ID, Type: wsTypeComplete})
+	}()
+
+	return true
+}
+
+// wsResponseFromResult converts the result of a single execution into a response message
+func wsResponseFromResult(id string, result BatchItemResult) WSResponse {
+	responseType := wsTypeResult
+	if result.Error != "" {
+		responseType = wsTypeError
+	}
+	return WSResponse{ID: id, Type: responseType, Status: result.Status, Rows: result.Rows, Error: result.Error}
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+)
+
+// slowExecutor records the highest number of concurrent executions
+type slowExecutor struct {
+	fakeExecutor
+	mu        sync.Mutex
+	active    int
+	maxActive int
+}
+
+func (e *slowExecutor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	e.mu.Lock()
+	e.active++
+	if e.active > e.maxActive {
+		e.maxActive = e.active
+	}
+	e.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	e.mu.Lock()
+	e.active--
+	e.mu.Unlock()
+	return e.fakeExecutor.Execute(ctx, queryConfig, params)
+}
+
+// dialTestWebSocket serves the WebSocket endpoint of the server and connects to it
+func dialTestWebSocket(t *testing.T, server *Server) *websocket.Conn {
+	t.Helper()
+
+	httpServer := httptest.NewServer(server.chain().Wrap(server.handleWebSocket))
+	t.Cleanup(httpServer.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readWSResponse reads the next response message with a timeout
+func readWSResponse(t *testing.T, conn *websocket.Conn) WSResponse {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var response WSResponse
+	if err := conn.ReadJSON(&response); err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	return response
+}
+
+func TestHandleWebSocket(t *testing.T) {
+	queries := map[string]config.Query{
+		"get_user": {
+			SQL:    "SELECT * FROM users WHERE id = :id",
+			Params: []config.QueryParam{{Name: "id", Type: "int"}},
+		},
+		"orders": {
+			SQL:            "SELECT count(*) FROM orders",
+			NotifyChannels: []string{"orders_changed"},
+		},
+	}
+
+	t.Run("CorrelatesResponses", func(t *testing.T) {
+		conn := dialTestWebSocket(t, newTestServer(&fakeExecutor{}, queries))
+
+		conn.WriteJSON(WSRequest{ID: "a", Type: "query", Query: "get_user", Params: map[string]interface{}{"id": 1}})
+		conn.WriteJSON(WSRequest{ID: "b", Type: "query", Query: "unknown"})
+
+		responses := make(map[string]WSResponse)
+		for i := 0; i < 2; i++ {
+			response := readWSResponse(t, conn)
+			responses[response.ID] = response
+		}
+
+		if responses["a"].Type != "result" || len(responses["a"].Rows) != 1 {
+			t.Errorf("expected result for 'a', got %+v", responses["a"])
+		}
+		if responses["b"].Type != "error" || responses["b"].Status != 404 {
+			t.Errorf("expected 404 error for 'b', got %+v", responses["b"])
+		}
+	})
+
+	t.Run("InvalidMessages", func(t *testing.T) {
+		conn := dialTestWebSocket(t, newTestServer(&fakeExecutor{}, queries))
+
+		conn.WriteMessage(websocket.TextMessage, []byte("not json"))
+		if response := readWSResponse(t, conn); response.Type != "error" || response.Error != "Invalid JSON message" {
+			t.Errorf("unexpected response: %+v", response)
+		}
+
+		conn.WriteJSON(WSRequest{Type: "query", Query: "get_user"})
+		if response := readWSResponse(t, conn); response.Error != "Message id is required" {
+			t.Errorf("unexpected response: %+v", response)
+		}
+
+		conn.WriteJSON(WSRequest{ID: "x", Type: "mutate"})
+		if response := readWSResponse(t, conn); response.ID != "x" || response.Error != "Unknown message type 'mutate'" {
+			t.Errorf("unexpected response: %+v", response)
+		}
+	})
+
+	t.Run("LimitsConcurrency", func(t *testing.T) {
+		executor := &slowExecutor{}
+		server := newTestServer(executor, queries)
+		server.webSocketConfig.MaxConcurrency = 2
+		conn := dialTestWebSocket(t, server)
+
+		for i := 0; i < 8; i++ {
+			conn.WriteJSON(WSRequest{ID: strconv.Itoa(i), Type: "query", Query: "get_user", Params: map[string]interface{}{"id": i}})
+		}
+		for i := 0; i < 8; i++ {
+			if response := readWSResponse(t, conn); response.Type != "result" {
+				t.Errorf("unexpected response: %+v", response)
+			}
+		}
+
+		if executor.maxActive > 2 {
+			t.Errorf("expected at most 2 concurrent executions, got %d", executor.maxActive)
+		}
+	})
+
+	t.Run("Subscriptions", func(t *testing.T) {
+		notifier := &fakeNotifier{}
+		server := newTestServer(&versionExecutor{}, queries)
+		server.notifier = notifier
+		server.webSocketConfig.MaxSubscriptions = 1
+		conn := dialTestWebSocket(t, server)
+
+		conn.WriteJSON(WSRequest{ID: "s1", Type: "subscribe", Query: "orders"})
+		if response := readWSResponse(t, conn); response.ID != "s1" || response.Type != "result" {
+			t.Fatalf("unexpected response: %+v", response)
+		}
+
+		conn.WriteJSON(WSRequest{ID: "s2", Type: "subscribe", Query: "orders"})
+		if response := readWSResponse(t, conn); response.ID != "s2" || response.Status != 429 {
+			t.Errorf("expected subscription limit error, got %+v", response)
+		}
+
+		conn.WriteJSON(WSRequest{ID: "s3", Type: "subscribe", Query: "get_user"})
+		if response := readWSResponse(t, conn); response.ID != "s3" || response.Status != 400 {
+			t.Errorf("expected unsupported subscription error, got %+v", response)
+		}
+
+		conn.WriteJSON(WSRequest{ID: "s1", Type: "unsubscribe"})
+		if response := readWSResponse(t, conn); response.ID != "s1" || response.Type != "complete" {
+			t.Errorf("expected complete, got %+v", response)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package slowlog
+
+import (
+	"context"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+// Executor logs the slow executions of a database executor, including executions inside
+// snapshots. It wraps another QueryExecutor; all other methods are passed through.
+type Executor struct {
+	query.QueryExecutor
+
+	log       *Log
+	explainer Explainer // nil if the wrapped executor cannot report statements and plans
+}
+
+// NewExecutor wraps an executor with the slow query log
+func (l *Log) NewExecutor(next query.QueryExecutor) *Executor {
+	explainer, _ := next.(Explainer)
+	return &Executor{QueryExecutor: next, log: l, explainer: explainer}
+}
+
+// Execute runs the query and logs it if it was slow
+func (e *Executor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	start := time.Now()
+	rows, err := e.QueryExecutor.Execute(ctx, queryConfig, params)
+	e.log.observe(ctx, e.explainer, queryConfig, params, start, err)
+	return rows, err
+}
+
+// Stream runs the query, passing its rows on as they are read, and logs it if it was slow
+func (e *Executor) Stream(ctx context.Context, queryConfig config.Query, params map[string]interface{}, columns func([]string) error, row func(map[string]interface{}) error) (int, error) {
+	start := time.Now()
+	count, err := query.Stream(ctx, e.QueryExecutor, queryConfig, params, columns, row)
+	e.log.observe(ctx, e.explainer, queryConfig, params, start, err)
+	return count, err
+}
+
+// BeginSnapshot starts a snapshot whose slow executions are logged
+func (e *Executor) BeginSnapshot(ctx context.Context) (query.Snapshot, error) {
+	snapshot, err := e.QueryExecutor.BeginSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshotExecutor{Snapshot: snapshot, executor: e}, nil
+}
+
+// snapshotExecutor logs the slow executions of a snapshot. Plans are captured outside
+// the snapshot.
+type snapshotExecutor struct {
+	query.Snapshot
+
+	executor *Executor
+}
+
+// Execute runs the query inside the snapshot and logs it if it was slow
+func (s *snapshotExecutor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	start := time.Now()
+	rows, err := s.Snapshot.Execute(ctx, queryConfig, params)
+	s.executor.log.observe(ctx, s.executor.explainer, queryConfig, params, start, err)
+	return rows, err
+}
@@ -0,0 +1,190 @@
+// Package slowlog logs query executions exceeding a duration threshold, optionally with
+// their EXPLAIN plan, and keeps the most recent ones for inspection
+package slowlog
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/logging"
+)
+
+const (
+	// defaultMaxEntries is the number of recent slow executions kept when not configured
+	defaultMaxEntries = 100
+
+	// maxConcurrentExplains limits the EXPLAIN statements running at the same time; plans
+	// of slow executions beyond the limit are not captured
+	maxConcurrentExplains = 2
+
+	// explainTimeout bounds a single EXPLAIN statement
+	explainTimeout = 10 * time.Second
+)
+
+// Explainer is implemented by executors that can report the statement sent to the
+// database for a query and the plan chosen for it
+type Explainer interface {
+	// Statement returns the SQL sent to the database for a query
+	Statement(queryConfig config.Query) string
+
+	// Explain returns the plan for a query and its parameters without executing it
+	Explain(ctx context.Context, queryConfig config.Query, params map[string]interface{}) (json.RawMessage, error)
+}
+
+// Entry describes a slow query execution
+type Entry struct {
+	Time        time.Time              `json:"time"`
+	RequestID   string                 `json:"request_id,omitempty"`
+	Query       string                 `json:"query"`
+	SQL         string                 `json:"sql"`
+	Params      map[string]interface{} `json:"params,omitempty"` // Omitted when parameter logging is disabled
+	DurationMs  float64                `json:"duration_ms"`
+	ThresholdMs float64                `json:"threshold_ms"`
+	Error       string                 `json:"error,omitempty"`
+	Plan        json.RawMessage        `json:"plan,omitempty"`
+	PlanError   string                 `json:"plan_error,omitempty"`
+}
+
+// Log records slow executions and keeps the most recent ones
+type Log struct {
+	threshold  time.Duration
+	explain    bool
+	maxEntries int
+	explains   chan struct{} // semaphore for running EXPLAIN statements
+
+	mu      sync.Mutex
+	entries []*Entry // ring buffer, next points to the oldest entry once full
+	next    int
+}
+
+// New creates a slow query log. Queries declaring a slow_threshold are logged against it,
+// other queries against the global threshold, if any.
+func New(slowQueryConfig config.SlowQueryConfig) *Log {
+	maxEntries := slowQueryConfig.MaxEntries
+	if maxEntries == 0 {
+		maxEntries = defaultMaxEntries
+	}
+
+	return &Log{
+		threshold:  slowQueryConfig.Threshold,
+		explain:    slowQueryConfig.Explain,
+		maxEntries: maxEntries,
+		explains:   make(chan struct{}, maxConcurrentExplains),
+		entries:    make([]*Entry, 0, maxEntries),
+	}
+}
+
+// Enabled reports whether any execution can be logged as slow
+func Enabled(slowQueryConfig config.SlowQueryConfig, queriesConfig *config.QueriesConfig) bool {
+	if slowQueryConfig.Threshold > 0 {
+		return true
+	}
+	for _, queryConfig := range queriesConfig.Queries {
+		if queryConfig.SlowThreshold > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// thresholdFor returns the threshold applying to a query, or zero if it is never logged
+func (l *Log) thresholdFor(queryConfig config.Query) time.Duration {
+	if queryConfig.SlowThreshold > 0 {
+		return queryConfig.SlowThreshold
+	}
+	return l.threshold
+}
+
+// observe logs an execution if it exceeded its threshold. The plan is captured in the
+// background with the original parameters, which are not kept.
+func (l *Log) observe(ctx context.Context, explainer Explainer, queryConfig config.Query, params map[string]interface{}, start time.Time, err error) {
+	duration := time.Since(start)
+	threshold := l.thresholdFor(queryConfig)
+	if threshold <= 0 || duration < threshold {
+		return
+	}
+
+	statement := queryConfig.SQL
+	if explainer != nil {
+		statement = explainer.Statement(queryConfig)
+	}
+	entry := &Entry{
+		Time:        start.UTC(),
+		RequestID:   logging.RequestID(ctx),
+		Query:       queryConfig.Name,
+		SQL:         statement,
+		DurationMs:  float64(duration.Microseconds()) / 1000,
+		ThresholdMs: float64(threshold.Microseconds()) / 1000,
+	}
+	if logging.ParametersEnabled() {
+		entry.Params = logging.Redact(queryConfig, params)
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	slog.WarnContext(ctx, "Slow query",
+		"query", queryConfig.Name,
+		"duration_ms", entry.DurationMs,
+		"threshold_ms", entry.ThresholdMs,
+		"sql", statement,
+		logging.Params(queryConfig, params),
+	)
+	l.add(entry)
+
+	if !l.explain || explainer == nil {
+		return
+	}
+	select {
+	case l.explains <- struct{}{}:
+	default:
+		slog.DebugContext(ctx, "Skipping EXPLAIN of slow query, too many running", "query", queryConfig.Name)
+		return
+	}
+	go func() {
+		defer func() { <-l.explains }()
+
+		// The request may be finished by the time the plan is captured
+		explainCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), explainTimeout)
+		defer cancel()
+
+		plan, err := explainer.Explain(explainCtx, queryConfig, params)
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if err != nil {
+			entry.PlanError = err.Error()
+			slog.WarnContext(ctx, "Failed to explain slow query", "query", queryConfig.Name, "error", err)
+			return
+		}
+		entry.Plan = plan
+	}()
+}
+
+// add keeps an entry, replacing the oldest one when full
+func (l *Log) add(entry *Entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.entries) < l.maxEntries {
+		l.entries = append(l.entries, entry)
+		return
+	}
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % l.maxEntries
+}
+
+// Recent returns the kept slow executions, most recent first
+func (l *Log) Recent() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	recent := make([]Entry, 0, len(l.entries))
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		recent = append(recent, *l.entries[(l.next+i)%len(l.entries)])
+	}
+	return recent
+}
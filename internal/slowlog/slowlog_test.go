@@ -0,0 +1,125 @@
+package slowlog
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/logging"
+	"github.com/shogotsuneto/simple-query-server/internal/query"
+)
+
+// sleepExecutor sleeps for the duration given in the "sleep" parameter and explains
+// every query with a fixed plan
+type sleepExecutor struct {
+	query.QueryExecutor
+}
+
+func (e *sleepExecutor) Execute(ctx context.Context, queryConfig config.Query, params map[string]interface{}) ([]map[string]interface{}, error) {
+	if sleep, ok := params["sleep"].(time.Duration); ok {
+		time.Sleep(sleep)
+	}
+	return nil, nil
+}
+
+func (e *sleepExecutor) Statement(queryConfig config.Query) string {
+	return "SELECT pg_sleep($1)"
+}
+
+func (e *sleepExecutor) Explain(ctx context.Context, queryConfig config.Query, params map[string]interface{}) (json.RawMessage, error) {
+	return json.RawMessage(`[{"Plan":{"Node Type":"Result"}}]`), nil
+}
+
+func TestExecutor(t *testing.T) {
+	log := New(config.SlowQueryConfig{Threshold: 20 * time.Millisecond})
+	executor := log.NewExecutor(&sleepExecutor{})
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+
+	fast := config.Query{Name: "fast"}
+	strict := config.Query{
+		Name:          "strict",
+		SlowThreshold: time.Millisecond,
+		Params:        []config.QueryParam{{Name: "token", Type: "string", Sensitive: true}},
+	}
+
+	executor.Execute(ctx, fast, map[string]interface{}{"sleep": time.Duration(0)})
+	executor.Execute(ctx, fast, map[string]interface{}{"sleep": 30 * time.Millisecond})
+	executor.Execute(ctx, strict, map[string]interface{}{"sleep": 5 * time.Millisecond, "token": "secret"})
+
+	recent := log.Recent()
+	if len(recent) != 2 {
+		t.Fatalf("expected 2 slow executions, got %d: %+v", len(recent), recent)
+	}
+
+	// Most recent first
+	if recent[0].Query != "strict" || recent[0].ThresholdMs != 1 {
+		t.Errorf("expected strict query against its own threshold, got %+v", recent[0])
+	}
+	if recent[0].Params["token"] != "[REDACTED]" {
+		t.Errorf("expected sensitive param to be redacted, got %v", recent[0].Params["token"])
+	}
+	if recent[1].Query != "fast" || recent[1].DurationMs < 30 || recent[1].ThresholdMs != 20 {
+		t.Errorf("expected fast query against the global threshold, got %+v", recent[1])
+	}
+	if recent[1].SQL != "SELECT pg_sleep($1)" || recent[1].RequestID != "req-1" {
+		t.Errorf("expected statement and request id to be recorded, got %+v", recent[1])
+	}
+	if recent[1].Plan != nil {
+		t.Errorf("expected no plan when explain is disabled, got %s", recent[1].Plan)
+	}
+}
+
+func TestExecutor_Explain(t *testing.T) {
+	log := New(config.SlowQueryConfig{Threshold: time.Nanosecond, Explain: true})
+	executor := log.NewExecutor(&sleepExecutor{})
+
+	executor.Execute(context.Background(), config.Query{Name: "users"}, map[string]interface{}{"sleep": time.Millisecond})
+
+	// Plans are captured in the background
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if recent := log.Recent(); len(recent) == 1 && recent[0].Plan != nil {
+			if string(recent[0].Plan) != `[{"Plan":{"Node Type":"Result"}}]` {
+				t.Errorf("unexpected plan: %s", recent[0].Plan)
+			}
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("expected the plan of the slow execution to be captured")
+}
+
+func TestLog_MaxEntries(t *testing.T) {
+	log := New(config.SlowQueryConfig{Threshold: time.Nanosecond, MaxEntries: 3})
+	executor := log.NewExecutor(&sleepExecutor{})
+
+	for _, name := range []string{"q1", "q2", "q3", "q4", "q5"} {
+		executor.Execute(context.Background(), config.Query{Name: name}, map[string]interface{}{"sleep": time.Millisecond})
+	}
+
+	recent := log.Recent()
+	if len(recent) != 3 {
+		t.Fatalf("expected 3 kept entries, got %d", len(recent))
+	}
+	for i, expected := range []string{"q5", "q4", "q3"} {
+		if recent[i].Query != expected {
+			t.Errorf("expected entry %d to be %s, got %s", i, expected, recent[i].Query)
+		}
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	queriesConfig := &config.QueriesConfig{Queries: map[string]config.Query{"users": {}}}
+	if Enabled(config.SlowQueryConfig{}, queriesConfig) {
+		t.Error("expected the slow query log to be disabled without thresholds")
+	}
+	if !Enabled(config.SlowQueryConfig{Threshold: time.Second}, queriesConfig) {
+		t.Error("expected the slow query log to be enabled by the global threshold")
+	}
+	queriesConfig.Queries["orders"] = config.Query{SlowThreshold: time.Second}
+	if !Enabled(config.SlowQueryConfig{}, queriesConfig) {
+		t.Error("expected the slow query log to be enabled by a query threshold")
+	}
+}
@@ -0,0 +1,138 @@
+// Package tracing exports OpenTelemetry traces and records a span per HTTP request.
+// Other packages create their spans with the global tracer provider, which does
+// nothing unless tracing is set up.
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName         = "github.com/shogotsuneto/simple-query-server/internal/tracing"
+	defaultServiceName = "simple-query-server"
+)
+
+// Setup installs a global tracer provider exporting spans to an OTLP/HTTP collector, and
+// the W3C trace context propagator so incoming traceparent headers continue their traces.
+// The returned function flushes pending spans and stops the export.
+func Setup(ctx context.Context, tracingConfig config.TracingConfig) (func(context.Context) error, error) {
+	options := make([]otlptracehttp.Option, 0, 2)
+	if tracingConfig.Endpoint != "" {
+		options = append(options, otlptracehttp.WithEndpoint(tracingConfig.Endpoint))
+	}
+	if tracingConfig.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	serviceName := tracingConfig.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	sampleRatio := tracingConfig.SampleRatio
+	if sampleRatio == 0 {
+		sampleRatio = 1
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+		// Requests that are part of a sampled trace are always sampled
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+// Handler records a server span for every request, continuing the trace of an incoming
+// traceparent header. The span is named after the matched route once the request was served.
+func Handler(next http.Handler) http.Handler {
+	tracer := otel.Tracer(tracerName)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		sw := &statusWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		r = r.WithContext(ctx)
+		next.ServeHTTP(sw, r)
+
+		// The mux records the matched pattern on the request it was given
+		if r.Pattern != "" {
+			span.SetName(r.Method + " " + r.Pattern)
+			span.SetAttributes(semconv.HTTPRoute(r.Pattern))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(sw.statusCode))
+		if sw.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(sw.statusCode))
+		}
+	})
+}
+
+// statusWriter records the status code of a response
+type statusWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.statusCode = statusCode
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush passes flushes through for streaming responses
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack passes connection takeovers through for WebSocket upgrades
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	w.statusCode = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the underlying response writer for http.ResponseController
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/shogotsuneto/simple-query-server/internal/config"
+	"github.com/shogotsuneto/simple-query-server/internal/middleware"
+	"go.opentelemetry.io/otel"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// collector is an in-process stand-in for an OTLP/HTTP collector
+type collector struct {
+	mu    sync.Mutex
+	spans []*tracepb.Span
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var request collectortrace.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	for _, resourceSpans := range request.ResourceSpans {
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			c.spans = append(c.spans, scopeSpans.Spans...)
+		}
+	}
+	c.mu.Unlock()
+
+	response, _ := proto.Marshal(&collectortrace.ExportTraceServiceResponse{})
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Write(response)
+}
+
+// span returns the received span with the given name
+func (c *collector) span(name string) *tracepb.Span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, span := range c.spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestTracing(t *testing.T) {
+	received := &collector{}
+	collectorServer := httptest.NewServer(received)
+	defer collectorServer.Close()
+
+	shutdown, err := Setup(context.Background(), config.TracingConfig{
+		Enabled:  true,
+		Endpoint: strings.TrimPrefix(collectorServer.URL, "http://"),
+		Insecure: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to set up tracing: %v", err)
+	}
+
+	chain := middleware.Chain{
+		middleware.NewHTTPHeaderMiddleware(middleware.HTTPHeaderConfig{Header: "X-User-ID", Parameter: "user_id", Required: true}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/query/{name}", chain.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		_, span := otel.Tracer("test").Start(r.Context(), "db.query users")
+		span.End()
+		w.WriteHeader(http.StatusOK)
+	}))
+	handler := Handler(mux)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodPost, "/query/users", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	req.Header.Set("X-User-ID", "123")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	rejected := httptest.NewRequest(http.MethodPost, "/query/users", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), rejected)
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("failed to shut down tracing: %v", err)
+	}
+
+	requestSpan := received.span("POST /query/{name}")
+	middlewareSpan := received.span("middleware http-header(X-User-ID->user_id)")
+	querySpan := received.span("db.query users")
+	if requestSpan == nil || middlewareSpan == nil || querySpan == nil {
+		t.Fatalf("expected request, middleware and query spans, got %v", received.spans)
+	}
+
+	if hex.EncodeToString(requestSpan.TraceId) != traceID || hex.EncodeToString(requestSpan.ParentSpanId) != "00f067aa0ba902b7" {
+		t.Errorf("expected the request span to continue the incoming trace")
+	}
+	if string(middlewareSpan.ParentSpanId) != string(requestSpan.SpanId) {
+		t.Errorf("expected the middleware span to be a child of the request span")
+	}
+	if string(querySpan.ParentSpanId) != string(requestSpan.SpanId) {
+		t.Errorf("expected the query span to be a child of the request span, not of the middleware span")
+	}
+
+	// The rejected request starts a new trace whose middleware span records the rejection
+	errorSpans := 0
+	received.mu.Lock()
+	for _, span := range received.spans {
+		if span.Status.GetCode() == tracepb.Status_STATUS_CODE_ERROR {
+			errorSpans++
+		}
+	}
+	received.mu.Unlock()
+	if errorSpans != 1 {
+		t.Errorf("expected one span recording the rejection, got %d", errorSpans)
+	}
+}